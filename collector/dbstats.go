@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"database/sql"
+
+	"github.com/luismfonseca/simetrics"
+)
+
+// Tracks the connection pool statistics of `db`, on every `TrackVarsPeriod`.
+// The pool usage is reported as values while the cumulative statistics (e.g. WaitCount) are reported as counts
+// with the difference since the previous period.
+func TrackDBStats(m *simetrics.SiMetrics, db *sql.DB, prefix string) simetrics.TrackingMetric {
+	reporter := dbStatsReporter{m: m, prefix: prefix, last: db.Stats()}
+
+	return m.TrackFunc(func() {
+		reporter.report(db.Stats())
+	})
+}
+
+type dbStatsReporter struct {
+	m      *simetrics.SiMetrics
+	prefix string
+	last   sql.DBStats
+}
+
+func (dsr *dbStatsReporter) report(stats sql.DBStats) {
+	dsr.m.Value(dsr.prefix+"open_connections", float64(stats.OpenConnections))
+	dsr.m.Value(dsr.prefix+"in_use", float64(stats.InUse))
+	dsr.m.Value(dsr.prefix+"idle", float64(stats.Idle))
+
+	dsr.m.Count(dsr.prefix+"wait_count", float64(stats.WaitCount-dsr.last.WaitCount))
+	dsr.m.Count(dsr.prefix+"wait_duration_ms", (stats.WaitDuration-dsr.last.WaitDuration).Seconds()*1000)
+	dsr.m.Count(dsr.prefix+"max_idle_closed", float64(stats.MaxIdleClosed-dsr.last.MaxIdleClosed))
+	dsr.m.Count(dsr.prefix+"max_lifetime_closed", float64(stats.MaxLifetimeClosed-dsr.last.MaxLifetimeClosed))
+
+	dsr.last = stats
+}
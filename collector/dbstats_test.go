@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDBStats(t *testing.T) {
+	Convey("The sql.DB stats reporter", t, func() {
+		m, mss := newStoreMetrics()
+		reporter := dbStatsReporter{m: m, prefix: "db.", last: sql.DBStats{WaitCount: 10, WaitDuration: time.Second, MaxIdleClosed: 1}}
+
+		Convey("should report the pool usage as values", func() {
+			reporter.report(sql.DBStats{OpenConnections: 5, InUse: 3, Idle: 2})
+
+			So(mss.values["db.open_connections"], ShouldEqual, 5)
+			So(mss.values["db.in_use"], ShouldEqual, 3)
+			So(mss.values["db.idle"], ShouldEqual, 2)
+		})
+
+		Convey("should report the cumulative stats as deltas between periods", func() {
+			reporter.report(sql.DBStats{WaitCount: 15, WaitDuration: 1500 * time.Millisecond, MaxIdleClosed: 1, MaxLifetimeClosed: 2})
+			reporter.report(sql.DBStats{WaitCount: 16, WaitDuration: 1600 * time.Millisecond, MaxIdleClosed: 4, MaxLifetimeClosed: 2})
+
+			So(mss.counts["db.wait_count"], ShouldEqual, 6)
+			So(mss.counts["db.wait_duration_ms"], ShouldAlmostEqual, 600)
+			So(mss.counts["db.max_idle_closed"], ShouldEqual, 3)
+			So(mss.counts["db.max_lifetime_closed"], ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"encoding/json"
+	"expvar"
+	"path"
+	"sync"
+
+	"github.com/luismfonseca/simetrics"
+)
+
+// The names of the variables published by `PublishExpvar`, skipped by the collector so that the metrics aren't
+// re-imported as new ones on every period
+var published sync.Map
+
+type ExpvarOptions struct {
+	// Prefix for every reported metric, defaults to `expvar.`
+	Prefix string
+
+	// Glob patterns (see `path.Match`) of the variables to report, all by default. Nested values are named by joining
+	// the keys with dots, e.g. `memstats.HeapAlloc`.
+	Include []string
+
+	// Glob patterns of the variables to skip, applied after `Include`
+	Exclude []string
+}
+
+// Reports the numeric `expvar` variables, including the numeric values nested in maps, as values on every
+// `TrackVarsPeriod`
+func TrackExpvar(m *simetrics.SiMetrics, opts ExpvarOptions) simetrics.TrackingMetric {
+	if opts.Prefix == "" {
+		opts.Prefix = "expvar."
+	}
+
+	return m.TrackFunc(func() {
+		collectExpvar(m, opts)
+	})
+}
+
+func collectExpvar(m *simetrics.SiMetrics, opts ExpvarOptions) {
+	expvar.Do(func(kv expvar.KeyValue) {
+		if _, ok := published.Load(kv.Key); ok {
+			return
+		}
+
+		var value interface{}
+		if json.Unmarshal([]byte(kv.Value.String()), &value) != nil {
+			return
+		}
+
+		flattenNumbers(kv.Key, value, func(name string, value float64) {
+			if matchesAny(opts.Include, name, true) && !matchesAny(opts.Exclude, name, false) {
+				m.Value(opts.Prefix+name, value)
+			}
+		})
+	})
+}
+
+// Calls `f` for every number in `value`, named by its keys joined with dots. Arrays are skipped.
+func flattenNumbers(name string, value interface{}, f func(name string, value float64)) {
+	switch v := value.(type) {
+	case float64:
+		f(name, v)
+	case bool:
+		if v {
+			f(name, 1)
+		} else {
+			f(name, 0)
+		}
+	case map[string]interface{}:
+		for key, nested := range v {
+			flattenNumbers(name+"."+key, nested, f)
+		}
+	}
+}
+
+func matchesAny(patterns []string, name string, matchIfEmpty bool) bool {
+	if len(patterns) == 0 {
+		return matchIfEmpty
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Publishes the cumulative counts and the last values aggregated by simetrics as the `expvar` variable `name`,
+// e.g. for tools reading `/debug/vars`. Only the sinks that aggregate in-process (Librato and stdout) support it.
+// Like `expvar.Publish`, it panics if the name is already in use. `TrackExpvar` skips it.
+func PublishExpvar(m *simetrics.SiMetrics, name string) {
+	published.Store(name, struct{}{})
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		snapshots, ok := m.Snapshot()
+		if !ok {
+			return nil
+		}
+
+		return map[string]map[string]float64{
+			"counts": snapshots.Cumulative.Counts,
+			"values": snapshots.Cumulative.Values,
+		}
+	}))
+}
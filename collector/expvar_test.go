@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luismfonseca/simetrics"
+	"github.com/luismfonseca/simetrics/sink"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func init() {
+	expvar.NewInt("test_requests").Set(42)
+	expvar.NewFloat("test_load").Set(0.5)
+	expvar.NewString("test_version").Set("1.2.3")
+
+	queues := expvar.NewMap("test_queues")
+	queues.Add("emails", 3)
+	queues.Add("pushes", 7)
+}
+
+// Counts the published variables, so that their names are unique when the tests are run with -count
+var publishedExpvars atomic.Int32
+
+func TestExpvarCollector(t *testing.T) {
+	Convey("The expvar collector", t, func() {
+		m, mss := newStoreMetrics()
+
+		Convey("should report the numeric variables, including the ones nested in maps", func() {
+			collectExpvar(m, ExpvarOptions{Prefix: "expvar.", Include: []string{"test_*"}})
+
+			So(mss.values["expvar.test_requests"], ShouldEqual, 42)
+			So(mss.values["expvar.test_load"], ShouldEqual, 0.5)
+			So(mss.values["expvar.test_queues.emails"], ShouldEqual, 3)
+			So(mss.values["expvar.test_queues.pushes"], ShouldEqual, 7)
+			So(mss.values, ShouldNotContainKey, "expvar.test_version")
+			So(mss.values, ShouldNotContainKey, "expvar.memstats.HeapAlloc")
+		})
+
+		Convey("should skip the excluded variables", func() {
+			collectExpvar(m, ExpvarOptions{Prefix: "expvar.", Include: []string{"test_*"}, Exclude: []string{"test_queues.p*", "test_load"}})
+
+			So(mss.values["expvar.test_requests"], ShouldEqual, 42)
+			So(mss.values["expvar.test_queues.emails"], ShouldEqual, 3)
+			So(mss.values, ShouldNotContainKey, "expvar.test_queues.pushes")
+			So(mss.values, ShouldNotContainKey, "expvar.test_load")
+		})
+
+		Convey("should report every numeric variable by default", func() {
+			collectExpvar(m, ExpvarOptions{Prefix: "expvar."})
+
+			So(mss.values, ShouldContainKey, "expvar.test_requests")
+			So(mss.values, ShouldContainKey, "expvar.memstats.HeapAlloc")
+		})
+	})
+
+	Convey("PublishExpvar", t, func() {
+		Convey("should publish the cumulative counts and values of an aggregating sink", func() {
+			m, err := simetrics.NewBuilder(simetrics.MetricsOptions{}, sink.NewMetricsSinkStdout(sink.NewLogrusLogger(logrus.NewEntry(logrus.New())))).Build()
+			So(err, ShouldBeNil)
+
+			name := fmt.Sprintf("test_simetrics_%d", publishedExpvars.Add(1))
+			PublishExpvar(m, name)
+			m.Count("requests", 2)
+			m.Count("requests", 3)
+			m.Value("queue_size", 9)
+
+			var published map[string]map[string]float64
+			So(json.Unmarshal([]byte(expvar.Get(name).String()), &published), ShouldBeNil)
+			So(published["counts"]["requests"], ShouldEqual, 5)
+			So(published["values"]["queue_size"], ShouldEqual, 9)
+		})
+
+		Convey("should not be collected back by TrackExpvar", func() {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			m, err := simetrics.NewBuilder(simetrics.MetricsOptions{TrackVarsPeriod: time.Millisecond}, sink.NewMetricsSinkStdout(sink.NewLogrusLogger(logrus.NewEntry(logger)))).Build()
+			So(err, ShouldBeNil)
+			Reset(m.StopAllTrackingMetrics)
+
+			name := fmt.Sprintf("test_simetrics_%d", publishedExpvars.Add(1))
+			PublishExpvar(m, name)
+			m.Value("queue_size", 9)
+			TrackExpvar(m, ExpvarOptions{})
+
+			ticks := make(chan struct{}, 100)
+			m.TrackFunc(func() { ticks <- struct{}{} })
+			for i := 0; i < 5; i++ {
+				<-ticks
+			}
+
+			snapshots, ok := m.Snapshot()
+			So(ok, ShouldBeTrue)
+			So(snapshots.Cumulative.Values, ShouldContainKey, "expvar.test_requests")
+			for valueName := range snapshots.Cumulative.Values {
+				So(valueName, ShouldNotStartWith, "expvar."+name)
+			}
+		})
+	})
+}
@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/luismfonseca/simetrics"
+)
+
+const (
+	// USER_HZ, the unit of the cpu times in `/proc/<pid>/stat`. It's 100 on virtually every Linux build.
+	userHz = 100
+)
+
+type ProcessOptions struct {
+	// Prefix for every reported metric, defaults to `process.`
+	Prefix string
+
+	// The proc directory of the process to inspect, defaults to `/proc/self`
+	ProcPath string
+
+	// The root of the cgroup filesystem, defaults to `/sys/fs/cgroup`. Both cgroup v1 and v2 layouts are supported.
+	CgroupPath string
+}
+
+// Collects resource usage of a process from procfs and of its container from the cgroup filesystem
+type ProcessCollector struct {
+	m        *simetrics.SiMetrics
+	opts     ProcessOptions
+	pageSize float64
+	last     map[string]float64 // previous readings of monotonic counters, to report deltas
+}
+
+func NewProcessCollector(m *simetrics.SiMetrics, opts ProcessOptions) *ProcessCollector {
+	if opts.Prefix == "" {
+		opts.Prefix = "process."
+	}
+	if opts.ProcPath == "" {
+		opts.ProcPath = "/proc/self"
+	}
+	if opts.CgroupPath == "" {
+		opts.CgroupPath = "/sys/fs/cgroup"
+	}
+
+	pc := &ProcessCollector{m: m, opts: opts, pageSize: float64(os.Getpagesize()), last: map[string]float64{}}
+
+	// take a first reading so the first `Collect()` reports deltas and not the totals since the process started
+	pc.collectCounters(func(string, float64) {})
+
+	return pc
+}
+
+// Periodically collects the process metrics, on every `TrackVarsPeriod`
+func (pc *ProcessCollector) Track() simetrics.TrackingMetric {
+	return pc.m.TrackFunc(pc.Collect)
+}
+
+// Reports the current readings. Metrics that can't be read (e.g. not running in a cgroup) are skipped.
+func (pc *ProcessCollector) Collect() {
+	if stat, err := pc.readStat(); err == nil {
+		pc.m.Value(pc.opts.Prefix+"resident_memory_bytes", stat.rssPages*pc.pageSize)
+		pc.m.Value(pc.opts.Prefix+"threads", stat.threads)
+	}
+
+	if fds, err := os.ReadDir(filepath.Join(pc.opts.ProcPath, "fd")); err == nil {
+		pc.m.Value(pc.opts.Prefix+"open_fds", float64(len(fds)))
+	}
+
+	if usage, limit, err := pc.readCgroupMemory(); err == nil {
+		pc.m.Value(pc.opts.Prefix+"cgroup.memory_usage_bytes", usage)
+		if limit > 0 {
+			pc.m.Value(pc.opts.Prefix+"cgroup.memory_limit_bytes", limit)
+		}
+	}
+
+	pc.collectCounters(pc.m.Count)
+}
+
+// Reads the monotonic counters and reports the difference since the last reading
+func (pc *ProcessCollector) collectCounters(report func(name string, value float64)) {
+	counters := map[string]float64{}
+
+	if stat, err := pc.readStat(); err == nil {
+		counters["cpu_seconds"] = stat.cpuSeconds
+	}
+	if throttledPeriods, throttledSeconds, err := pc.readCgroupCPUThrottling(); err == nil {
+		counters["cgroup.cpu_throttled_periods"] = throttledPeriods
+		counters["cgroup.cpu_throttled_seconds"] = throttledSeconds
+	}
+
+	for name, value := range counters {
+		last, ok := pc.last[name]
+		pc.last[name] = value
+
+		if ok && value >= last { // a decrease means the counter was reset, skip it
+			report(pc.opts.Prefix+name, value-last)
+		}
+	}
+}
+
+type procStat struct {
+	cpuSeconds float64
+	threads    float64
+	rssPages   float64
+}
+
+// Parses `/proc/<pid>/stat`, see proc(5)
+func (pc *ProcessCollector) readStat() (procStat, error) {
+	data, err := os.ReadFile(filepath.Join(pc.opts.ProcPath, "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+
+	// the command name is in parenthesis and may contain spaces, so only split what comes after it
+	line := string(data)
+	fields := strings.Fields(line[strings.LastIndexByte(line, ')')+1:])
+	if len(fields) < 22 {
+		return procStat{}, &os.PathError{Op: "parse", Path: filepath.Join(pc.opts.ProcPath, "stat"), Err: os.ErrInvalid}
+	}
+
+	// `fields[0]` is the 3rd field of the file (state)
+	values := make([]float64, len(fields))
+	for _, i := range []int{11, 12, 17, 21} {
+		values[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return procStat{}, err
+		}
+	}
+
+	return procStat{
+		cpuSeconds: (values[11] + values[12]) / userHz, // utime + stime
+		threads:    values[17],
+		rssPages:   values[21],
+	}, nil
+}
+
+func (pc *ProcessCollector) isCgroupV2() bool {
+	_, err := os.Stat(filepath.Join(pc.opts.CgroupPath, "cgroup.controllers"))
+	return err == nil
+}
+
+// Returns the memory usage and limit of the cgroup, the limit is 0 when there is none
+func (pc *ProcessCollector) readCgroupMemory() (usage, limit float64, err error) {
+	usageFile, limitFile := "memory/memory.usage_in_bytes", "memory/memory.limit_in_bytes"
+	if pc.isCgroupV2() {
+		usageFile, limitFile = "memory.current", "memory.max"
+	}
+
+	usage, err = readFloatFile(filepath.Join(pc.opts.CgroupPath, usageFile))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// "max" on v2, and a huge page-aligned number on v1 means unlimited
+	limit, err = readFloatFile(filepath.Join(pc.opts.CgroupPath, limitFile))
+	if err != nil || limit >= 1<<62 {
+		limit = 0
+	}
+
+	return usage, limit, nil
+}
+
+// Returns the number of throttled periods and the total throttled time in seconds
+func (pc *ProcessCollector) readCgroupCPUThrottling() (periods, seconds float64, err error) {
+	if pc.isCgroupV2() {
+		stats, err := readKeyValueFile(filepath.Join(pc.opts.CgroupPath, "cpu.stat"))
+		if err != nil {
+			return 0, 0, err
+		}
+		return stats["nr_throttled"], stats["throttled_usec"] / 1e6, nil
+	}
+
+	// the cpu controller is frequently co-mounted with cpuacct
+	for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+		stats, err := readKeyValueFile(filepath.Join(pc.opts.CgroupPath, dir, "cpu.stat"))
+		if err == nil {
+			return stats["nr_throttled"], stats["throttled_time"] / 1e9, nil
+		}
+	}
+
+	return 0, 0, os.ErrNotExist
+}
+
+func readFloatFile(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+}
+
+// Parses files with a `key value` pair per line, such as `cpu.stat`
+func readKeyValueFile(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+
+	return values, scanner.Err()
+}
@@ -0,0 +1,12 @@
+//go:build linux
+
+package collector
+
+import (
+	"github.com/luismfonseca/simetrics"
+)
+
+// Tracks the resource usage of the current process and of its container, on every `TrackVarsPeriod`
+func TrackProcess(m *simetrics.SiMetrics) simetrics.TrackingMetric {
+	return NewProcessCollector(m, ProcessOptions{}).Track()
+}
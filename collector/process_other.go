@@ -0,0 +1,12 @@
+//go:build !linux
+
+package collector
+
+import (
+	"github.com/luismfonseca/simetrics"
+)
+
+// procfs and cgroups are Linux only, so there is nothing to track
+func TrackProcess(m *simetrics.SiMetrics) simetrics.TrackingMetric {
+	return simetrics.TrackingMetric(func() {})
+}
@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luismfonseca/simetrics"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stores the last value it received for each metric, counts are accumulated
+type MetricsSinkStore struct {
+	counts map[string]float64
+	values map[string]float64
+}
+
+func (mss *MetricsSinkStore) Init() error {
+	mss.counts = map[string]float64{}
+	mss.values = map[string]float64{}
+	return nil
+}
+
+func (mss *MetricsSinkStore) ReportCount(name string, value float64)        { mss.counts[name] += value }
+func (mss *MetricsSinkStore) ReportValue(name string, value float64)        { mss.values[name] = value }
+func (mss *MetricsSinkStore) ReportDistribution(name string, value float64) {}
+
+func newStoreMetrics() (*simetrics.SiMetrics, *MetricsSinkStore) {
+	mss := &MetricsSinkStore{}
+	m, err := simetrics.NewBuilder(simetrics.MetricsOptions{}, mss).Build()
+	So(err, ShouldBeNil)
+	return m, mss
+}
+
+func TestProcessCollector(t *testing.T) {
+	Convey("A ProcessCollector", t, func() {
+		m, mss := newStoreMetrics()
+
+		Convey("should report the process metrics from procfs", func() {
+			pc := NewProcessCollector(m, ProcessOptions{ProcPath: "testdata/proc", CgroupPath: "testdata/none"})
+			pc.Collect()
+
+			So(mss.values["process.resident_memory_bytes"], ShouldEqual, 2048*float64(os.Getpagesize()))
+			So(mss.values["process.threads"], ShouldEqual, 12)
+			So(mss.values["process.open_fds"], ShouldEqual, 4)
+			So(mss.values, ShouldNotContainKey, "process.cgroup.memory_usage_bytes")
+		})
+
+		Convey("should report the cpu time as a delta between collections", func() {
+			procDir := t.TempDir()
+			stat, err := os.ReadFile("testdata/proc/stat")
+			So(err, ShouldBeNil)
+			So(os.WriteFile(filepath.Join(procDir, "stat"), stat, 0o644), ShouldBeNil)
+
+			pc := NewProcessCollector(m, ProcessOptions{ProcPath: procDir, CgroupPath: "testdata/none"})
+			pc.Collect()
+			So(mss.counts["process.cpu_seconds"], ShouldEqual, 0)
+
+			// utime goes from 250 to 400 ticks and stime from 50 to 100
+			stat = []byte(strings.Replace(string(stat), " 250 50 ", " 400 100 ", 1))
+			So(os.WriteFile(filepath.Join(procDir, "stat"), stat, 0o644), ShouldBeNil)
+			pc.Collect()
+			So(mss.counts["process.cpu_seconds"], ShouldEqual, 2)
+		})
+
+		Convey("should report the cgroup v2 memory", func() {
+			pc := NewProcessCollector(m, ProcessOptions{ProcPath: "testdata/proc", CgroupPath: "testdata/cgroupv2"})
+			pc.Collect()
+
+			So(mss.values["process.cgroup.memory_usage_bytes"], ShouldEqual, 104857600)
+			So(mss.values["process.cgroup.memory_limit_bytes"], ShouldEqual, 536870912)
+			So(pc.last["cgroup.cpu_throttled_periods"], ShouldEqual, 10)
+			So(pc.last["cgroup.cpu_throttled_seconds"], ShouldEqual, 2)
+		})
+
+		Convey("should report the cgroup v1 memory, ignoring the unlimited limit", func() {
+			pc := NewProcessCollector(m, ProcessOptions{ProcPath: "testdata/proc", CgroupPath: "testdata/cgroupv1"})
+			pc.Collect()
+
+			So(mss.values["process.cgroup.memory_usage_bytes"], ShouldEqual, 52428800)
+			So(mss.values, ShouldNotContainKey, "process.cgroup.memory_limit_bytes")
+			So(pc.last["cgroup.cpu_throttled_periods"], ShouldEqual, 5)
+			So(pc.last["cgroup.cpu_throttled_seconds"], ShouldEqual, 1.5)
+		})
+	})
+}
@@ -0,0 +1,30 @@
+package simetrics
+
+import (
+	"context"
+)
+
+type contextKey struct{}
+
+// Returns a copy of `ctx` carrying `m`, e.g. with request-scoped tags, so that deeper code can report through it with
+// `FromContext`
+// Example usage, in a middleware:
+// ```
+// ctx := simetrics.NewContext(r.Context(), metric.WithTags("tenant:"+tenant))
+// next.ServeHTTP(w, r.WithContext(ctx))
+// ```
+func NewContext(ctx context.Context, m *SiMetrics) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// Returns the SiMetrics carried by `ctx`, or the default one (see `Default`) if there is none
+// Example usage:
+// ```
+// simetrics.FromContext(ctx).Increment("checkout.created") // with the tags of the request
+// ```
+func FromContext(ctx context.Context) *SiMetrics {
+	if m, ok := ctx.Value(contextKey{}).(*SiMetrics); ok && m != nil {
+		return m
+	}
+	return Default()
+}
@@ -0,0 +1,88 @@
+package simetrics
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContext(t *testing.T) {
+	Convey("A context", t, func() {
+		msMock := MetricsSinkMock{}
+		m, err := NewBuilder(MetricsOptions{TrackVarsPeriod: 10 * time.Millisecond}, &msMock).Build()
+		So(err, ShouldBeNil)
+		Reset(m.StopAllTrackingMetrics)
+
+		Convey("should carry a SiMetrics with its tags", func() {
+			tagged := m.WithTags("tenant:acme")
+			ctx := NewContext(context.Background(), tagged)
+
+			msMock.OnReportCountTagged("checkout.created", 1, []string{"tenant:acme"}).Return().Once()
+			FromContext(ctx).Increment("checkout.created")
+
+			msMock.AssertExpectations(t)
+		})
+
+		Convey("should fall back to the default SiMetrics", func() {
+			So(FromContext(context.Background()), ShouldEqual, Default())
+
+			SetDefault(m)
+			Reset(func() { SetDefault(nil) })
+			So(FromContext(context.Background()), ShouldEqual, m)
+		})
+
+		// Counts the calls of a tracking function, signaling each one on `ticks`
+		counting := func(calls *atomic.Int32, ticks chan struct{}) func() {
+			return func() {
+				calls.Add(1)
+				ticks <- struct{}{}
+			}
+		}
+
+		Convey("should stop the tracking functions when the parent is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			var calls atomic.Int32
+			entered, proceed := make(chan struct{}), make(chan struct{})
+			m.TrackFuncContext(ctx, func() {
+				calls.Add(1)
+				entered <- struct{}{}
+				<-proceed
+			})
+
+			<-entered // the tracking is blocked in the call until the parent is done
+			cancel()
+			close(proceed) // so it stops as soon as it checks the parent again
+
+			// the other tracking keeps firing meanwhile, giving the stopped one a few periods to misbehave
+			var otherCalls atomic.Int32
+			otherTicks := make(chan struct{}, 100)
+			m.TrackFunc(counting(&otherCalls, otherTicks))
+			for i := 0; i < 3; i++ {
+				<-otherTicks
+			}
+
+			So(calls.Load(), ShouldEqual, 1)
+			So(otherCalls.Load(), ShouldBeGreaterThanOrEqualTo, 3)
+		})
+
+		Convey("should not stop the tracking functions of the other contexts", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			var cancelledCalls atomic.Int32
+			m.TrackFuncContext(ctx, counting(&cancelledCalls, make(chan struct{}, 100)))
+
+			var calls atomic.Int32
+			ticks := make(chan struct{}, 100)
+			m.TrackFuncContext(context.Background(), counting(&calls, ticks))
+			for i := 0; i < 3; i++ {
+				<-ticks
+			}
+
+			So(cancelledCalls.Load(), ShouldEqual, 0)
+			So(calls.Load(), ShouldBeGreaterThanOrEqualTo, 3)
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package simetrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var defaultMetrics atomic.Pointer[SiMetrics]
+
+func init() {
+	defaultMetrics.Store(NewEmpty())
+}
+
+// Returns the default SiMetrics, used by the package-level functions. It's `NewEmpty()` until `SetDefault` is called.
+func Default() *SiMetrics {
+	return defaultMetrics.Load()
+}
+
+// Sets the default SiMetrics, used by the package-level functions, e.g. so that libraries can report metrics without
+// being given one. The calls in-flight keep using the previous default. A nil `m` resets it to `NewEmpty()`.
+// Example usage:
+// ```
+// simetrics.SetDefault(simetrics.FromConfig(conf, log))
+// ```
+func SetDefault(m *SiMetrics) {
+	if m == nil {
+		m = NewEmpty()
+	}
+	defaultMetrics.Store(m)
+}
+
+// Like `SiMetrics.Count`, on the default SiMetrics
+func Count(name string, value float64) {
+	Default().Count(name, value)
+}
+
+// Like `SiMetrics.Increment`, on the default SiMetrics
+func Increment(name string) {
+	Default().Increment(name)
+}
+
+// Like `SiMetrics.Value`, on the default SiMetrics
+func Value(name string, value float64) {
+	Default().Value(name, value)
+}
+
+// Like `SiMetrics.Distribution`, on the default SiMetrics
+func Distribution(name string, value float64) {
+	Default().Distribution(name, value)
+}
+
+// Like `SiMetrics.TimeSince`, on the default SiMetrics
+// Example usage:
+// ```
+// defer simetrics.TimeSince("checkout.latency_ms", time.Now())
+// ```
+func TimeSince(name string, startTime time.Time) {
+	Default().TimeSince(name, startTime)
+}
@@ -0,0 +1,72 @@
+package simetrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDefault(t *testing.T) {
+	Convey("The default SiMetrics", t, func() {
+		Reset(func() {
+			SetDefault(nil)
+		})
+
+		Convey("should be empty initially", func() {
+			So(Default(), ShouldNotBeNil)
+			Increment("nothing") // doesn't panic
+		})
+
+		Convey("should receive the package-level calls once set", func() {
+			msMock := MetricsSinkMock{}
+			m, err := NewBuilder(MetricsOptions{NamespaceFormat: "lib."}, &msMock).Build()
+			So(err, ShouldBeNil)
+			SetDefault(m)
+
+			msMock.OnReportCount("lib.requests", 1).Return().Once()
+			msMock.OnReportCount("lib.bytes", 512).Return().Once()
+			msMock.OnReportValue("lib.queue_size", 3).Return().Once()
+			msMock.OnReportDistribution("lib.size", 42).Return().Once()
+			msMock.On("ReportDistribution", "lib.latency_ms", mock.Anything).Return().Once()
+
+			Increment("requests")
+			Count("bytes", 512)
+			Value("queue_size", 3)
+			Distribution("size", 42)
+			TimeSince("latency_ms", time.Now())
+
+			msMock.AssertExpectations(t)
+		})
+
+		Convey("should be reset to an empty one with nil", func() {
+			SetDefault(nil)
+			So(Default(), ShouldNotBeNil)
+		})
+
+		Convey("should be swappable while in use", func() {
+			msMock := MetricsSinkMock{}
+			msMock.OnReportCount("requests", 1).Return()
+			m, err := NewBuilder(MetricsOptions{}, &msMock).Build()
+			So(err, ShouldBeNil)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						Increment("requests")
+					}
+				}()
+			}
+			for i := 0; i < 10; i++ {
+				SetDefault(m)
+				SetDefault(nil)
+			}
+			wg.Wait()
+		})
+	})
+}
@@ -0,0 +1,185 @@
+package httpmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/luismfonseca/simetrics"
+)
+
+type operationKey struct{}
+
+// Returns a context whose requests are tagged with the given `operation` by the instrumented `http.RoundTripper`
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+// Returns the operation set with `WithOperation`, or `unknown`
+func OperationFromContext(r *http.Request) string {
+	if operation, ok := r.Context().Value(operationKey{}).(string); ok {
+		return operation
+	}
+	return "unknown"
+}
+
+type ClientOptions struct {
+	// Prefix for every reported metric, defaults to `http.client.`
+	Prefix string
+
+	// Returns the operation name used to tag the metrics. Defaults to `OperationFromContext`.
+	OperationName func(r *http.Request) string
+}
+
+// An `http.RoundTripper` that reports, for every request:
+//   - `requests`, a count tagged by `host`, `operation` and `status_class` (`error` if no response was received)
+//   - `latency_ms`, a distribution of the time until the response headers were received, with the same tags
+//   - `errors`, a count tagged by `host`, `operation` and `error` (`dns`, `connect`, `tls`, `timeout`, `canceled` or `other`)
+//   - `dns_ms`, `connect_ms` and `tls_ms`, distributions of the time spent on new connections tagged by `host`
+type RoundTripper struct {
+	next http.RoundTripper
+	m    *simetrics.SiMetrics
+	opts ClientOptions
+}
+
+// Wraps `next` (or `http.DefaultTransport` if nil) with instrumentation.
+// Example usage:
+// ```
+// client := &http.Client{Transport: httpmetrics.NewRoundTripper(metric, nil, httpmetrics.ClientOptions{})}
+// req, _ := http.NewRequestWithContext(httpmetrics.WithOperation(ctx, "create_order"), "POST", url, body)
+// client.Do(req)
+// ```
+func NewRoundTripper(m *simetrics.SiMetrics, next http.RoundTripper, opts ClientOptions) *RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "http.client."
+	}
+	if opts.OperationName == nil {
+		opts.OperationName = OperationFromContext
+	}
+
+	return &RoundTripper{next: next, m: m, opts: opts}
+}
+
+func (rt *RoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	host := rt.m.WithTags("host:" + r.URL.Hostname())
+	tagged := host.WithTags("operation:" + rt.opts.OperationName(r))
+
+	trace := &connectionTrace{}
+	r = r.WithContext(httptrace.WithClientTrace(r.Context(), trace.clientTrace()))
+
+	tStart := time.Now()
+	resp, err := rt.next.RoundTrip(r)
+
+	trace.report(host, rt.opts.Prefix)
+	if err != nil {
+		tagged.WithTags("error:" + trace.errorCategory(err)).Increment(rt.opts.Prefix + "errors")
+		tagged = tagged.WithTags("status_class:error")
+	} else {
+		tagged = tagged.WithTags("status_class:" + StatusClass(resp.StatusCode))
+	}
+	tagged.Increment(rt.opts.Prefix + "requests")
+	tagged.Distribution(rt.opts.Prefix+"latency_ms", time.Since(tStart).Seconds()*1000) // not TimeSince, whose unit can be changed
+
+	return resp, err
+}
+
+// Keeps the timings and failures of the connection phases.
+// The callbacks may be called concurrently, e.g. when dialing several addresses.
+type connectionTrace struct {
+	mutex      sync.Mutex
+	dnsStart   time.Time
+	dns        time.Duration
+	dnsErr     error
+	connect    time.Duration
+	connectErr error
+	tlsStart   time.Time
+	tls        time.Duration
+	tlsErr     error
+}
+
+func (ct *connectionTrace) clientTrace() *httptrace.ClientTrace {
+	connectStarts := map[string]time.Time{}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			ct.mutex.Lock()
+			defer ct.mutex.Unlock()
+			ct.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			ct.mutex.Lock()
+			defer ct.mutex.Unlock()
+			ct.dns, ct.dnsErr = time.Since(ct.dnsStart), info.Err
+		},
+		ConnectStart: func(network, addr string) {
+			ct.mutex.Lock()
+			defer ct.mutex.Unlock()
+			connectStarts[network+addr] = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			ct.mutex.Lock()
+			defer ct.mutex.Unlock()
+			if err != nil {
+				ct.connectErr = err
+			} else if ct.connect == 0 {
+				ct.connect = time.Since(connectStarts[network+addr])
+			}
+		},
+		TLSHandshakeStart: func() {
+			ct.mutex.Lock()
+			defer ct.mutex.Unlock()
+			ct.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			ct.mutex.Lock()
+			defer ct.mutex.Unlock()
+			ct.tls, ct.tlsErr = time.Since(ct.tlsStart), err
+		},
+	}
+}
+
+// Reports the time spent in each phase of a new connection, nothing is reported when a connection is reused
+func (ct *connectionTrace) report(m *simetrics.SiMetrics, prefix string) {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	if ct.dns > 0 && ct.dnsErr == nil {
+		m.Distribution(prefix+"dns_ms", ct.dns.Seconds()*1000)
+	}
+	if ct.connect > 0 {
+		m.Distribution(prefix+"connect_ms", ct.connect.Seconds()*1000)
+	}
+	if ct.tls > 0 && ct.tlsErr == nil {
+		m.Distribution(prefix+"tls_ms", ct.tls.Seconds()*1000)
+	}
+}
+
+// Returns the category of a transport error based on the phase in which it failed
+func (ct *connectionTrace) errorCategory(err error) string {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case ct.dnsErr != nil:
+		return "dns"
+	case ct.tlsErr != nil:
+		return "tls"
+	case ct.connectErr != nil:
+		return "connect"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,70 @@
+package httpmetrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRoundTripper(t *testing.T) {
+	Convey("The instrumented http.RoundTripper", t, func() {
+		m, mss := newStoreMetrics()
+		client := &http.Client{Transport: NewRoundTripper(m, &http.Transport{}, ClientOptions{})}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				<-time.After(100 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		get := func(ctx context.Context, url string) {
+			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+			So(err, ShouldBeNil)
+
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+
+		Convey("should report the requests tagged by host, operation and status class", func() {
+			get(WithOperation(context.Background(), "create"), server.URL)
+			get(context.Background(), server.URL)
+
+			So(mss.counts["http.client.requests|host:127.0.0.1,operation:create,status_class:2xx"], ShouldEqual, 1)
+			So(mss.counts["http.client.requests|host:127.0.0.1,operation:unknown,status_class:2xx"], ShouldEqual, 1)
+			So(mss.distributions["http.client.latency_ms|host:127.0.0.1,operation:create,status_class:2xx"], ShouldHaveLength, 1)
+			So(mss.distributions["http.client.connect_ms|host:127.0.0.1"], ShouldHaveLength, 1) // the second reuses the connection
+		})
+
+		Convey("should categorize the transport errors", func() {
+			closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			closedListener.Close()
+
+			tlsServer := httptest.NewTLSServer(http.NotFoundHandler()) // untrusted certificate
+			defer tlsServer.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			get(context.Background(), "http://"+closedListener.Addr().String())
+			get(context.Background(), tlsServer.URL)
+			get(ctx, server.URL+"/slow")
+			get(context.Background(), "http://simetrics.invalid")
+
+			So(mss.counts["http.client.errors|host:127.0.0.1,operation:unknown,error:connect"], ShouldEqual, 1)
+			So(mss.counts["http.client.errors|host:127.0.0.1,operation:unknown,error:tls"], ShouldEqual, 1)
+			So(mss.counts["http.client.errors|host:127.0.0.1,operation:unknown,error:timeout"], ShouldEqual, 1)
+			So(mss.counts["http.client.errors|host:simetrics.invalid,operation:unknown,error:dns"], ShouldEqual, 1)
+			So(mss.counts["http.client.requests|host:127.0.0.1,operation:unknown,status_class:error"], ShouldEqual, 3)
+		})
+	})
+}
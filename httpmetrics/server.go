@@ -0,0 +1,152 @@
+package httpmetrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/luismfonseca/simetrics"
+)
+
+type ServerOptions struct {
+	// Prefix for every reported metric, defaults to `http.server.`
+	Prefix string
+
+	// Returns the route name used to tag the metrics. It's called after the request was served, so it can use
+	// routing information set by the handlers. Defaults to `RoutePattern`.
+	RouteName func(r *http.Request) string
+}
+
+// Returns the `http.ServeMux` pattern that matched the request, or `unmatched`.
+// Raw paths would make the number of tags unbounded, so they are never used.
+func RoutePattern(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// Returns the status class of a status code, e.g. `2xx`
+func StatusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// Wraps `next` to report, for every request:
+//   - `requests`, a count
+//   - `latency_ms`, a distribution of the time to serve it
+//   - `response_size_bytes`, a distribution of the bytes written in the body
+//
+// all tagged by `route`, `method` and `status_class`, and `in_flight`, the number of requests being served.
+// Example usage:
+// ```
+// mux := http.NewServeMux()
+// mux.HandleFunc("GET /devices/{id}", getDevice)
+// http.ListenAndServe(":8080", httpmetrics.Middleware(metric, httpmetrics.ServerOptions{})(mux))
+// ```
+func Middleware(m *simetrics.SiMetrics, opts ServerOptions) func(http.Handler) http.Handler {
+	if opts.Prefix == "" {
+		opts.Prefix = "http.server."
+	}
+	if opts.RouteName == nil {
+		opts.RouteName = RoutePattern
+	}
+
+	var inFlight int64
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tStart := time.Now()
+			m.Value(opts.Prefix+"in_flight", float64(atomic.AddInt64(&inFlight, 1)))
+
+			rw, recorder := wrapResponseWriter(w)
+			defer func() {
+				m.Value(opts.Prefix+"in_flight", float64(atomic.AddInt64(&inFlight, -1)))
+
+				tagged := m.WithTags(
+					"route:"+opts.RouteName(r),
+					"method:"+r.Method,
+					"status_class:"+StatusClass(recorder.status),
+				)
+				tagged.Increment(opts.Prefix + "requests")
+				tagged.Distribution(opts.Prefix+"latency_ms", time.Since(tStart).Seconds()*1000) // not TimeSince, whose unit can be changed
+				tagged.Distribution(opts.Prefix+"response_size_bytes", float64(recorder.size))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// Records the status and size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(b)
+	rr.size += int64(n)
+	return n, err
+}
+
+// Allows `http.ResponseController` to reach the original `http.ResponseWriter`
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+type flusher struct{ *responseRecorder }
+
+func (f flusher) Flush() {
+	f.wroteHeader = true
+	f.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijacker struct{ *responseRecorder }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// Returns a `http.ResponseWriter` that records into the returned `responseRecorder`, implementing `http.Flusher`
+// and `http.Hijacker` only if `w` also does, so type assertions by the handlers keep behaving the same.
+func wrapResponseWriter(w http.ResponseWriter) (http.ResponseWriter, *responseRecorder) {
+	rr := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+
+	switch {
+	case isFlusher && isHijacker:
+		return struct {
+			*responseRecorder
+			http.Flusher
+			http.Hijacker
+		}{rr, flusher{rr}, hijacker{rr}}, rr
+	case isFlusher:
+		return struct {
+			*responseRecorder
+			http.Flusher
+		}{rr, flusher{rr}}, rr
+	case isHijacker:
+		return struct {
+			*responseRecorder
+			http.Hijacker
+		}{rr, hijacker{rr}}, rr
+	default:
+		return rr, rr
+	}
+}
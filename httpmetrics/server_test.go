@@ -0,0 +1,118 @@
+package httpmetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismfonseca/simetrics"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stores every report it receives, with the tags joined to the name
+type MetricsSinkStore struct {
+	counts        map[string]float64
+	distributions map[string][]float64
+}
+
+func (mss *MetricsSinkStore) Init() error {
+	mss.counts = map[string]float64{}
+	mss.distributions = map[string][]float64{}
+	return nil
+}
+
+func (mss *MetricsSinkStore) ReportCount(name string, value float64)        { mss.counts[name] += value }
+func (mss *MetricsSinkStore) ReportValue(name string, value float64)        {}
+func (mss *MetricsSinkStore) ReportDistribution(name string, value float64) {}
+
+func (mss *MetricsSinkStore) ReportCountTagged(name string, value float64, tags []string) {
+	mss.counts[name+"|"+strings.Join(tags, ",")] += value
+}
+
+func (mss *MetricsSinkStore) ReportValueTagged(name string, value float64, tags []string) {}
+
+func (mss *MetricsSinkStore) ReportDistributionTagged(name string, value float64, tags []string) {
+	key := name + "|" + strings.Join(tags, ",")
+	mss.distributions[key] = append(mss.distributions[key], value)
+}
+
+func newStoreMetrics() (*simetrics.SiMetrics, *MetricsSinkStore) {
+	mss := &MetricsSinkStore{}
+	m, err := simetrics.NewBuilder(simetrics.MetricsOptions{}, mss).Build()
+	So(err, ShouldBeNil)
+	return m, mss
+}
+
+func TestMiddleware(t *testing.T) {
+	Convey("The http server middleware", t, func() {
+		m, mss := newStoreMetrics()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /devices/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, isFlusher := w.(http.Flusher)
+			_, isHijacker := w.(http.Hijacker)
+			So(isFlusher, ShouldBeTrue)
+			So(isHijacker, ShouldBeFalse)
+
+			_, _ = w.Write([]byte("device"))
+		})
+		mux.HandleFunc("POST /devices", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.WriteHeader(http.StatusInternalServerError) // superfluous, ignored
+		})
+		handler := Middleware(m, ServerOptions{})(mux)
+
+		Convey("should report the requests tagged by route pattern, method and status class", func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/devices/1", nil))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/devices/2", nil))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/devices", nil))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+
+			So(mss.counts["http.server.requests|route:GET /devices/{id},method:GET,status_class:2xx"], ShouldEqual, 2)
+			So(mss.counts["http.server.requests|route:POST /devices,method:POST,status_class:4xx"], ShouldEqual, 1)
+			So(mss.counts["http.server.requests|route:unmatched,method:GET,status_class:4xx"], ShouldEqual, 1)
+			So(mss.distributions["http.server.latency_ms|route:GET /devices/{id},method:GET,status_class:2xx"], ShouldHaveLength, 2)
+			So(mss.distributions["http.server.response_size_bytes|route:GET /devices/{id},method:GET,status_class:2xx"], ShouldResemble, []float64{6, 6})
+		})
+
+		Convey("should allow a custom route name", func() {
+			handler := Middleware(m, ServerOptions{
+				Prefix:    "api.",
+				RouteName: func(r *http.Request) string { return "devices" },
+			})(mux)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/devices/1", nil))
+
+			So(mss.counts["api.requests|route:devices,method:GET,status_class:2xx"], ShouldEqual, 1)
+		})
+
+		Convey("should report the latency in milliseconds, regardless of the TimeUnit", func() {
+			mss := &MetricsSinkStore{}
+			m, err := simetrics.NewBuilder(simetrics.MetricsOptions{TimeUnit: time.Nanosecond}, mss).Build()
+			So(err, ShouldBeNil)
+			Middleware(m, ServerOptions{})(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/devices/1", nil))
+
+			latencies := mss.distributions["http.server.latency_ms|route:GET /devices/{id},method:GET,status_class:2xx"]
+			So(latencies, ShouldHaveLength, 1)
+			So(latencies[0], ShouldBeLessThan, 1000) // generous, in nanoseconds it would be way above
+		})
+	})
+
+	Convey("The wrapped http.ResponseWriter", t, func() {
+		Convey("should only implement the interfaces of the original", func() {
+			w, _ := wrapResponseWriter(struct{ http.ResponseWriter }{httptest.NewRecorder()})
+			_, isFlusher := w.(http.Flusher)
+			_, isHijacker := w.(http.Hijacker)
+			So(isFlusher, ShouldBeFalse)
+			So(isHijacker, ShouldBeFalse)
+		})
+
+		Convey("should be unwrappable by http.ResponseController", func() {
+			recorder := httptest.NewRecorder()
+			w, _ := wrapResponseWriter(recorder)
+
+			So(w.(interface{ Unwrap() http.ResponseWriter }).Unwrap(), ShouldEqual, recorder)
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package httpmetrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/luismfonseca/simetrics"
+	"github.com/luismfonseca/simetrics/sink"
+	"github.com/luismfonseca/simetrics/type/distribution"
+)
+
+type distributionView struct {
+	Count float64 `json:"count"`
+	Mean  float64 `json:"mean"`
+	Sd    float64 `json:"sd"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+type histogramView struct {
+	Bounds           []float64 `json:"bounds"`
+	CumulativeCounts []float64 `json:"cumulative_counts"` // the last one is the total count
+	distributionView
+}
+
+type snapshotView struct {
+	Start         time.Time                   `json:"start"`
+	End           *time.Time                  `json:"end,omitempty"`
+	Counts        map[string]float64          `json:"counts"`
+	Values        map[string]float64          `json:"values"`
+	Distributions map[string]distributionView `json:"distributions"`
+	Sets          map[string]float64          `json:"sets"`
+	Histograms    map[string]histogramView    `json:"histograms"`
+}
+
+type snapshotsView struct {
+	Last       *snapshotView `json:"last"`
+	Current    *snapshotView `json:"current"`
+	Cumulative *snapshotView `json:"cumulative"`
+}
+
+// Returns a `http.Handler` that renders the metrics aggregated in-process as JSON, with the last completed interval,
+// the current one and the totals since the start. Responds with 501 if the sink doesn't aggregate in-process, and with
+// 500 if the metrics can't be encoded, e.g. for infinite values.
+// Example usage:
+// ```
+// adminMux.Handle("GET /debug/metrics", httpmetrics.SnapshotHandler(metric))
+// ```
+func SnapshotHandler(m *simetrics.SiMetrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshots, ok := m.Snapshot()
+		if !ok {
+			http.Error(w, "The metrics backend doesn't support snapshots", http.StatusNotImplemented)
+			return
+		}
+
+		body, err := json.Marshal(snapshotsView{
+			Last:       newSnapshotView(snapshots.Last),
+			Current:    newSnapshotView(snapshots.Current),
+			Cumulative: newSnapshotView(snapshots.Cumulative),
+		})
+		if err != nil {
+			http.Error(w, "Failed to encode the metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(append(body, '\n'))
+	})
+}
+
+func newSnapshotView(snapshot *sink.Snapshot) *snapshotView {
+	if snapshot == nil {
+		return nil
+	}
+
+	view := &snapshotView{
+		Start:         snapshot.Start,
+		Counts:        snapshot.Counts,
+		Values:        snapshot.Values,
+		Distributions: map[string]distributionView{},
+		Sets:          snapshot.Sets,
+		Histograms:    map[string]histogramView{},
+	}
+	if !snapshot.End.IsZero() {
+		view.End = &snapshot.End
+	}
+	for name, dist := range snapshot.Distributions {
+		view.Distributions[name] = newDistributionView(dist)
+	}
+	for name, histogram := range snapshot.Histograms {
+		view.Histograms[name] = histogramView{
+			Bounds:           histogram.Bounds,
+			CumulativeCounts: histogram.CumulativeCounts(),
+			distributionView: newDistributionView(&histogram.Distribution),
+		}
+	}
+
+	return view
+}
+
+func newDistributionView(dist *distribution.Distribution) distributionView {
+	return distributionView{Count: dist.N, Mean: dist.Mean(), Sd: dist.Sd(), Min: dist.Min, Max: dist.Max}
+}
@@ -0,0 +1,61 @@
+package httpmetrics
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luismfonseca/simetrics"
+	"github.com/luismfonseca/simetrics/sink"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshotHandler(t *testing.T) {
+	Convey("The snapshot http.Handler", t, func() {
+		Convey("should render the aggregated metrics as JSON", func() {
+			log := logrus.New()
+			log.Out = io.Discard
+			m, err := simetrics.NewBuilder(simetrics.MetricsOptions{}, sink.NewMetricsSinkStdout(sink.NewLogrusLogger(logrus.NewEntry(log)))).Build()
+			So(err, ShouldBeNil)
+
+			m.Count("requests", 3)
+			m.Distribution("latency", 10)
+			m.Distribution("latency", 20)
+
+			w := httptest.NewRecorder()
+			SnapshotHandler(m).ServeHTTP(w, httptest.NewRequest("GET", "/debug/metrics", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+
+			var body map[string]map[string]interface{}
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+			So(body["last"], ShouldBeNil)
+			So(body["current"]["counts"], ShouldResemble, map[string]interface{}{"requests": 3.0})
+			So(body["cumulative"]["distributions"], ShouldResemble, map[string]interface{}{
+				"latency": map[string]interface{}{"count": 2.0, "mean": 15.0, "sd": 5.0, "min": 10.0, "max": 20.0},
+			})
+		})
+
+		Convey("should fail if the metrics can't be encoded", func() {
+			log := logrus.New()
+			log.Out = io.Discard
+			m, err := simetrics.NewBuilder(simetrics.MetricsOptions{}, sink.NewMetricsSinkStdout(sink.NewLogrusLogger(logrus.NewEntry(log)))).Build()
+			So(err, ShouldBeNil)
+
+			m.Value("ratio", math.Inf(1))
+
+			w := httptest.NewRecorder()
+			SnapshotHandler(m).ServeHTTP(w, httptest.NewRequest("GET", "/debug/metrics", nil))
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("should fail if the sink doesn't aggregate in-process", func() {
+			w := httptest.NewRecorder()
+			SnapshotHandler(simetrics.NewEmpty()).ServeHTTP(w, httptest.NewRequest("GET", "/debug/metrics", nil))
+			So(w.Code, ShouldEqual, http.StatusNotImplemented)
+		})
+	})
+}
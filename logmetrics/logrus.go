@@ -0,0 +1,71 @@
+package logmetrics
+
+import (
+	"fmt"
+
+	"github.com/luismfonseca/simetrics"
+	"github.com/luismfonseca/simetrics/sink"
+	"github.com/sirupsen/logrus"
+)
+
+type HookOptions struct {
+	// Prefix of the counters, defaults to `log.`
+	Prefix string
+
+	// A field whose value is added as a tag, e.g. `error_kind` for `error_kind:timeout`. The entries without it are
+	// counted untagged.
+	TagField string
+
+	// The levels counted, defaults to all of them
+	Levels []logrus.Level
+}
+
+// A logrus hook counting the log entries by level, as `log.<level>` (e.g. `log.error` or `log.warning`)
+type Hook struct {
+	m    *simetrics.SiMetrics
+	opts HookOptions
+}
+
+// Builds a hook counting the log entries through `m`. The entries logged by the sinks themselves (marked with the
+// `sink.LogField` field) are skipped, so that they can't loop back into the sinks.
+// Example usage:
+// ```
+// log.AddHook(logmetrics.NewHook(metric, logmetrics.HookOptions{TagField: "error_kind"}))
+// ```
+func NewHook(m *simetrics.SiMetrics, opts HookOptions) *Hook {
+	if opts.Prefix == "" {
+		opts.Prefix = "log."
+	}
+	if opts.Levels == nil {
+		opts.Levels = logrus.AllLevels
+	}
+
+	return &Hook{m: m, opts: opts}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return h.opts.Levels
+}
+
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if _, fromSink := entry.Data[sink.LogField]; fromSink {
+		return nil
+	}
+
+	m := h.m
+	if h.opts.TagField != "" {
+		if value, ok := entry.Data[h.opts.TagField]; ok {
+			m = m.WithTags(h.opts.TagField + ":" + tagValue(value))
+		}
+	}
+	m.Increment(h.opts.Prefix + entry.Level.String())
+
+	return nil
+}
+
+func tagValue(value interface{}) string {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,109 @@
+package logmetrics
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/luismfonseca/simetrics"
+	"github.com/luismfonseca/simetrics/sink"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stores the counts it receives, with the tags joined to the name
+type MetricsSinkStore struct {
+	counts map[string]float64
+	log    *logrus.Entry // logs on every report, like a misbehaving sink would
+}
+
+func (mss *MetricsSinkStore) Init() error {
+	mss.counts = map[string]float64{}
+	return nil
+}
+
+func (mss *MetricsSinkStore) ReportCount(name string, value float64) {
+	mss.ReportCountTagged(name, value, nil)
+}
+
+func (mss *MetricsSinkStore) ReportValue(name string, value float64)        {}
+func (mss *MetricsSinkStore) ReportDistribution(name string, value float64) {}
+
+func (mss *MetricsSinkStore) ReportCountTagged(name string, value float64, tags []string) {
+	if len(tags) > 0 {
+		name += "|" + strings.Join(tags, ",")
+	}
+	mss.counts[name] += value
+
+	if mss.log != nil {
+		mss.log.Warn("Reported a count")
+	}
+}
+
+func (mss *MetricsSinkStore) ReportValueTagged(name string, value float64, tags []string)        {}
+func (mss *MetricsSinkStore) ReportDistributionTagged(name string, value float64, tags []string) {}
+
+func newStoreMetrics() (*simetrics.SiMetrics, *MetricsSinkStore) {
+	mss := &MetricsSinkStore{}
+	m, err := simetrics.NewBuilder(simetrics.MetricsOptions{}, mss).Build()
+	So(err, ShouldBeNil)
+	return m, mss
+}
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	return logger
+}
+
+func TestHook(t *testing.T) {
+	Convey("The logrus hook", t, func() {
+		m, mss := newStoreMetrics()
+		logger := newLogger()
+
+		Convey("should count the log entries by level", func() {
+			logger.AddHook(NewHook(m, HookOptions{}))
+
+			logger.Info("starting")
+			logger.Warn("slow")
+			logger.WithError(errors.New("boom")).Error("failed")
+			logger.Error("failed again")
+
+			So(mss.counts, ShouldResemble, map[string]float64{"log.info": 1, "log.warning": 1, "log.error": 2})
+		})
+
+		Convey("should only count the given levels, with the prefix", func() {
+			logger.AddHook(NewHook(m, HookOptions{Prefix: "logs.", Levels: []logrus.Level{logrus.ErrorLevel}}))
+
+			logger.Info("starting")
+			logger.Error("failed")
+
+			So(mss.counts, ShouldResemble, map[string]float64{"logs.error": 1})
+		})
+
+		Convey("should tag the entries with the tag field", func() {
+			logger.AddHook(NewHook(m, HookOptions{TagField: "error_kind"}))
+
+			logger.WithField("error_kind", "timeout").Error("failed")
+			logger.WithField("error_kind", errors.New("refused")).Error("failed")
+			logger.Error("failed")
+
+			So(mss.counts, ShouldResemble, map[string]float64{
+				"log.error|error_kind:timeout": 1,
+				"log.error|error_kind:refused": 1,
+				"log.error":                    1,
+			})
+		})
+
+		Convey("should skip the entries logged by the sinks, not looping", func() {
+			logger.AddHook(NewHook(m, HookOptions{}))
+			mss.log = logger.WithField(sink.LogField, "store")
+
+			logger.Error("failed")
+
+			So(mss.counts, ShouldResemble, map[string]float64{"log.error": 1})
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package logmetrics
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/luismfonseca/simetrics"
+	"github.com/luismfonseca/simetrics/sink"
+)
+
+type HandlerOptions struct {
+	// Prefix of the counters, defaults to `log.`
+	Prefix string
+
+	// An attribute whose value is added as a tag, e.g. `error_kind` for `error_kind:timeout`. Only the top-level
+	// attributes, outside of groups, are used.
+	TagAttr string
+}
+
+// A slog handler counting the records by level before passing them to the next handler
+type handler struct {
+	m    *simetrics.SiMetrics
+	next slog.Handler
+	opts HandlerOptions
+
+	// from the attributes given to `WithAttrs`
+	fromSink bool
+	tag      string
+	grouped  bool
+}
+
+// Wraps `next` with a handler counting the records by level through `m`, as `log.<level>` with the same level names as
+// the logrus hook (`log.debug`, `log.info`, `log.warning` or `log.error`). The records logged by the sinks themselves
+// (with the `sink.LogField` attribute) are skipped, so that they can't loop back into the sinks.
+// Example usage:
+// ```
+// logger := slog.New(logmetrics.NewHandler(metric, slog.NewJSONHandler(os.Stderr, nil), logmetrics.HandlerOptions{}))
+// ```
+func NewHandler(m *simetrics.SiMetrics, next slog.Handler, opts HandlerOptions) slog.Handler {
+	if opts.Prefix == "" {
+		opts.Prefix = "log."
+	}
+
+	return &handler{m: m, next: next, opts: opts}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	fromSink, tag := h.fromSink, h.tag
+	r.Attrs(func(a slog.Attr) bool {
+		fromSink, tag = h.inspect(a, fromSink, tag)
+		return true
+	})
+
+	if !fromSink {
+		m := h.m
+		if tag != "" {
+			m = m.WithTags(h.opts.TagAttr + ":" + tag)
+		}
+		m.Increment(h.opts.Prefix + levelName(r.Level))
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	withAttrs := *h
+	withAttrs.next = h.next.WithAttrs(attrs)
+	for _, a := range attrs {
+		withAttrs.fromSink, withAttrs.tag = h.inspect(a, withAttrs.fromSink, withAttrs.tag)
+	}
+	return &withAttrs
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	withGroup := *h
+	withGroup.next = h.next.WithGroup(name)
+	withGroup.grouped = true
+	return &withGroup
+}
+
+// The sink marker is found whatever the grouping, while the tag attribute is only used outside of groups
+func (h *handler) inspect(a slog.Attr, fromSink bool, tag string) (bool, string) {
+	switch {
+	case a.Key == sink.LogField:
+		fromSink = true
+	case !h.grouped && h.opts.TagAttr != "" && a.Key == h.opts.TagAttr:
+		tag = a.Value.Resolve().String()
+	}
+	return fromSink, tag
+}
+
+// Returns the logrus name of the level, e.g. `warning` for `slog.LevelWarn`
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warning"
+	default:
+		return "error"
+	}
+}
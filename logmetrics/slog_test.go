@@ -0,0 +1,71 @@
+package logmetrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/luismfonseca/simetrics/sink"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHandler(t *testing.T) {
+	Convey("The slog handler", t, func() {
+		m, mss := newStoreMetrics()
+		next := slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+		Convey("should count the enabled records by level", func() {
+			logger := slog.New(NewHandler(m, next, HandlerOptions{}))
+
+			logger.Debug("not enabled")
+			logger.Info("starting")
+			logger.Warn("slow")
+			logger.Error("failed", "error", errors.New("boom"))
+			logger.Log(context.Background(), slog.LevelError+4, "failed badly")
+
+			So(mss.counts, ShouldResemble, map[string]float64{"log.info": 1, "log.warning": 1, "log.error": 2})
+		})
+
+		Convey("should tag the records with the tag attribute", func() {
+			logger := slog.New(NewHandler(m, next, HandlerOptions{Prefix: "logs.", TagAttr: "error_kind"}))
+
+			logger.Error("failed", "error_kind", "timeout")
+			logger.With("error_kind", errors.New("refused")).Error("failed")
+			logger.WithGroup("request").Error("failed", "error_kind", "nested")
+			logger.Error("failed")
+
+			So(mss.counts, ShouldResemble, map[string]float64{
+				"logs.error|error_kind:timeout": 1,
+				"logs.error|error_kind:refused": 1,
+				"logs.error":                    2,
+			})
+		})
+
+		Convey("should skip the records logged by the sinks, not looping", func() {
+			logger := slog.New(NewHandler(m, next, HandlerOptions{}))
+			sinkLogger := sink.NewSlogLogger(logger).WithField(sink.LogField, "store")
+
+			sinkLogger.Warn("Failed to post metrics")
+			logger.Warn("slow", sink.LogField, "store")
+			logger.Warn("slow")
+
+			So(mss.counts, ShouldResemble, map[string]float64{"log.warning": 1})
+
+			Convey("also from a grouped logger", func() {
+				sink.NewSlogLogger(logger.WithGroup("metrics")).WithField(sink.LogField, "store").Warn("Failed to post metrics")
+
+				So(mss.counts, ShouldResemble, map[string]float64{"log.warning": 1})
+			})
+		})
+
+		Convey("should skip the records logged by the sinks built by their constructors", func() {
+			logger := slog.New(NewHandler(m, next, HandlerOptions{}))
+
+			sink.NewMetricsSinkStdout(sink.NewSlogLogger(logger)).ReportEvent(sink.Event{Title: "Deploy"})
+
+			So(mss.counts, ShouldBeEmpty)
+		})
+	})
+}
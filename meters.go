@@ -0,0 +1,82 @@
+package simetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luismfonseca/simetrics/type/distribution"
+	"github.com/luismfonseca/simetrics/type/meter"
+)
+
+const (
+	windowSlots = 12
+)
+
+// The meters and windowed distributions, shared by all the SiMetrics derived from the same one
+type meters struct {
+	mutex   sync.Mutex
+	meters  map[string]*meter.Meter
+	windows map[string]*distribution.Window
+}
+
+func newMeters() *meters {
+	return &meters{meters: map[string]*meter.Meter{}, windows: map[string]*distribution.Window{}}
+}
+
+// Returns the Meter named `name`, creating it if needed, to read the rate of events in-process.
+// With `ReportMeters`, its rates are reported on every `TrackVarsPeriod` as `<name>.rate_1m`, `<name>.rate_5m` and
+// `<name>.rate_15m`.
+// Example usage:
+// ```
+// metric.Meter("requests").Mark(1)
+// // and somewhere else, e.g. for load shedding:
+// if metric.Meter("requests").Rate1() > maxRequestsPerSecond { ... }
+// ```
+func (m *SiMetrics) Meter(name string) *meter.Meter {
+	m.meters.mutex.Lock()
+	defer m.meters.mutex.Unlock()
+
+	mt, ok := m.meters.meters[m.prefix+name]
+	if !ok {
+		mt = meter.New()
+		m.meters.meters[m.prefix+name] = mt
+
+		if m.opts.ReportMeters {
+			m.TrackFunc(func() {
+				m.Value(name+".rate_1m", mt.Rate1())
+				m.Value(name+".rate_5m", mt.Rate5())
+				m.Value(name+".rate_15m", mt.Rate15())
+			})
+		}
+	}
+
+	return mt
+}
+
+// Returns the distribution over the last `window` named `name`, creating it if needed, to read recent stats
+// in-process. An existing one keeps its original window.
+// With `ReportMeters`, its stats are reported on every `TrackVarsPeriod` as `<name>.mean`, `<name>.max` and
+// `<name>.count`.
+func (m *SiMetrics) WindowedDistribution(name string, window time.Duration) *distribution.Window {
+	m.meters.mutex.Lock()
+	defer m.meters.mutex.Unlock()
+
+	w, ok := m.meters.windows[m.prefix+name]
+	if !ok {
+		w = distribution.NewWindow(window, windowSlots)
+		m.meters.windows[m.prefix+name] = w
+
+		if m.opts.ReportMeters {
+			m.TrackFunc(func() {
+				snapshot := w.Snapshot()
+				if snapshot.N > 0 {
+					m.Value(name+".mean", snapshot.Mean())
+					m.Value(name+".max", snapshot.Max)
+				}
+				m.Value(name+".count", snapshot.N)
+			})
+		}
+	}
+
+	return w
+}
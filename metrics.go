@@ -2,14 +2,12 @@ package simetrics
 
 import (
 	"context"
-	"fmt"
 	"math"
-	"os"
-	"path"
 	"strings"
 	"time"
 
 	"github.com/luismfonseca/simetrics/sink"
+	"github.com/sirupsen/logrus"
 )
 
 type MetricsOptions struct {
@@ -19,13 +17,24 @@ type MetricsOptions struct {
 	// A prefix with the app name to fill the `%s`
 	NamespaceFormat string
 
-	// computed namespaceFormat + app name
-	namespace string
+	// The unit of the reported durations, defaults to `time.Millisecond`
+	TimeUnit time.Duration
+
+	// The bucket bounds of the distributions that are also reported as histograms, by metric name prefix.
+	// When several prefixes match, the longest one is used.
+	HistogramBuckets map[string][]float64
+
+	// Whether to report the meters and windowed distributions on every `TrackVarsPeriod`
+	ReportMeters bool
 }
 
 type SiMetrics struct {
-	sink          sink.MetricsSink
+	state         *state
 	opts          MetricsOptions
+	prefix        string // added by `WithNamespacePrefix`, after the namespace
+	tags          []string
+	meters        *meters
+	log           sink.Logger
 	ctx           context.Context
 	ctxCancelFunc context.CancelFunc
 }
@@ -46,25 +55,39 @@ func NewBuilder(options MetricsOptions, ms sink.MetricsSink) *SiMetricsBuilder {
 	if options.TrackVarsPeriod == 0 {
 		options.TrackVarsPeriod = 5 * time.Second
 	}
+	if options.TimeUnit == 0 {
+		options.TimeUnit = time.Millisecond
+	}
 
 	ctx, ctxCancelFunc := context.WithCancel(context.Background())
 
-	return &SiMetricsBuilder{m: SiMetrics{sink: ms, opts: options, ctx: ctx, ctxCancelFunc: ctxCancelFunc}}
+	return &SiMetricsBuilder{m: SiMetrics{
+		state:         newState(ms, options),
+		opts:          options,
+		meters:        newMeters(),
+		log:           sink.NewLogrusLogger(logrus.NewEntry(logrus.StandardLogger())),
+		ctx:           ctx,
+		ctxCancelFunc: ctxCancelFunc,
+	}}
+}
+
+// Declares that the distributions with names starting with `prefix` are also reported as histograms with the
+// given bucket bounds, e.g. `distribution.ExponentialBounds(1, 2, 10)`
+func (mb *SiMetricsBuilder) WithHistogramBuckets(prefix string, bounds []float64) *SiMetricsBuilder {
+	if mb.m.opts.HistogramBuckets == nil {
+		mb.m.opts.HistogramBuckets = map[string][]float64{}
+	}
+	mb.m.opts.HistogramBuckets[prefix] = bounds
+	return mb
 }
 
 // Returns a built and fully initialized `SiMetrics` or an error
 func (mb *SiMetricsBuilder) Build() (*SiMetrics, error) {
-	err := mb.m.sink.Init()
+	err := mb.m.state.Load().sink.Init()
 	if err != nil {
 		return nil, err
 	}
 
-	if strings.Contains(mb.m.opts.NamespaceFormat, "%s") {
-		mb.m.opts.namespace = fmt.Sprintf(mb.m.opts.NamespaceFormat, path.Base(os.Args[0]))
-	} else {
-		mb.m.opts.namespace = mb.m.opts.NamespaceFormat
-	}
-
 	return &mb.m, nil
 }
 
@@ -76,37 +99,145 @@ func NewEmpty() *SiMetrics {
 
 func (m *SiMetrics) WithNamespacePrefix(prefix string) *SiMetrics {
 	shallowCopy := *m
-	shallowCopy.opts.namespace += prefix
+	shallowCopy.prefix += prefix
+	return &shallowCopy
+}
+
+// Returns a SiMetrics that adds the given tags (in the `key:value` format) to every metric.
+// Sinks that don't support tags will report the metrics untagged.
+func (m *SiMetrics) WithTags(tags ...string) *SiMetrics {
+	shallowCopy := *m
+	shallowCopy.tags = append(m.tags[:len(m.tags):len(m.tags)], tags...)
 	return &shallowCopy
 }
 
 func (m *SiMetrics) Count(name string, value float64) {
 	if !math.IsNaN(value) {
-		m.sink.ReportCount(m.opts.namespace+name, value)
+		ms, namespace, release := m.current()
+		defer release()
+		m.reportCount(ms, namespace+name, value)
 	}
 }
 
 func (m *SiMetrics) Increment(name string) {
-	m.sink.ReportCount(m.opts.namespace+name, 1.0)
+	ms, namespace, release := m.current()
+	defer release()
+	m.reportCount(ms, namespace+name, 1.0)
 }
 
 func (m *SiMetrics) Decrement(name string) {
-	m.sink.ReportCount(m.opts.namespace+name, -1.0)
+	ms, namespace, release := m.current()
+	defer release()
+	m.reportCount(ms, namespace+name, -1.0)
 }
 
 func (m *SiMetrics) Value(name string, value float64) {
 	if !math.IsNaN(value) {
-		m.sink.ReportValue(m.opts.namespace+name, value)
+		ms, namespace, release := m.current()
+		defer release()
+		m.reportValue(ms, namespace+name, value)
 	}
 }
 
 func (m *SiMetrics) Distribution(name string, value float64) {
 	if !math.IsNaN(value) {
-		m.sink.ReportDistribution(m.opts.namespace+name, value)
+		ms, namespace, release := m.current()
+		defer release()
+		m.reportDistribution(ms, namespace+name, value)
+
+		if bounds := m.histogramBounds(name); bounds != nil {
+			if hs, ok := ms.(sink.MetricsSinkHistograms); ok {
+				hs.ReportHistogram(namespace+name, value, bounds, m.tags)
+			}
+		}
+	}
+}
+
+// Like `Count`, but only a `rate` fraction (from 0 to 1) of the calls are reported, upweighted to account for the
+// dropped ones. Useful to reduce the overhead of hot paths.
+func (m *SiMetrics) CountSampled(name string, value, rate float64) {
+	if rate >= 1 {
+		m.Count(name, value)
+	} else if !math.IsNaN(value) && rate > 0 {
+		ms, namespace, release := m.current()
+		defer release()
+		if ss, ok := ms.(sink.MetricsSinkSampled); ok {
+			ss.ReportCountSampled(namespace+name, value, rate, m.tags)
+		} else if sink.ShouldSample(rate) {
+			m.reportCount(ms, namespace+name, value/rate)
+		}
+	}
+}
+
+// Like `Increment`, but sampled at `rate`. See `CountSampled`.
+func (m *SiMetrics) IncrementSampled(name string, rate float64) {
+	m.CountSampled(name, 1.0, rate)
+}
+
+// Like `Distribution`, but only a `rate` fraction (from 0 to 1) of the calls are reported, each one with a weight
+// of `1/rate` if the sink supports it, also to the histograms. Useful to reduce the overhead of hot paths.
+func (m *SiMetrics) DistributionSampled(name string, value, rate float64) {
+	if rate >= 1 {
+		m.Distribution(name, value)
+	} else if !math.IsNaN(value) && rate > 0 {
+		ms, namespace, release := m.current()
+		defer release()
+		if ss, ok := ms.(sink.MetricsSinkSampled); ok {
+			ss.ReportDistributionSampled(namespace+name, value, rate, m.tags)
+		} else if sink.ShouldSample(rate) {
+			m.reportDistribution(ms, namespace+name, value)
+		}
+
+		if bounds := m.histogramBounds(name); bounds != nil {
+			if hss, ok := ms.(sink.MetricsSinkHistogramsSampled); ok {
+				hss.ReportHistogramSampled(namespace+name, value, rate, bounds, m.tags)
+			} else if hs, ok := ms.(sink.MetricsSinkHistograms); ok && sink.ShouldSample(rate) {
+				hs.ReportHistogram(namespace+name, value, bounds, m.tags)
+			}
+		}
+	}
+}
+
+// Counts the unique members of a set, e.g. users or tenants, on every reporting interval.
+// Sinks that don't support sets ignore it.
+func (m *SiMetrics) Set(name string, member string) {
+	ms, namespace, release := m.current()
+	defer release()
+	if ss, ok := ms.(sink.MetricsSinkSets); ok {
+		ss.ReportSet(namespace+name, member, m.tags)
+	}
+}
+
+// Reports an event, such as a deploy or a config reload, to be shown alongside the metrics.
+// The tags are added to the ones of this SiMetrics. Sinks that don't support events ignore it.
+func (m *SiMetrics) Event(title, text string, tags []string, priority sink.EventPriority) {
+	ms, _, release := m.current()
+	defer release()
+	if es, ok := ms.(sink.MetricsSinkEvents); ok {
+		es.ReportEvent(sink.Event{
+			Title:    title,
+			Text:     text,
+			Tags:     append(m.tags[:len(m.tags):len(m.tags)], tags...),
+			Priority: priority,
+		})
+	}
+}
+
+// Reports the health status of a service or dependency. Sinks that don't support service checks ignore it.
+func (m *SiMetrics) ServiceCheck(name string, status sink.ServiceCheckStatus, message string) {
+	ms, namespace, release := m.current()
+	defer release()
+	if scs, ok := ms.(sink.MetricsSinkServiceChecks); ok {
+		scs.ReportServiceCheck(sink.ServiceCheck{
+			Name:    namespace + name,
+			Status:  status,
+			Message: message,
+			Tags:    m.tags,
+		})
 	}
 }
 
-// Measures the time in ms since the given `startTime`
+// Measures the time since the given `startTime`, in the `TimeUnit` (ms by default)
 // Example usage:
 // ```
 // tStart := time.Now()
@@ -119,20 +250,32 @@ func (m *SiMetrics) Distribution(name string, value float64) {
 // defer metric.TimeSince("name", tStart)
 // ```
 func (m *SiMetrics) TimeSince(name string, startTime time.Time) {
-	m.sink.ReportDistribution(m.opts.namespace+name, time.Since(startTime).Seconds()*1000)
+	m.Distribution(name, m.inTimeUnit(time.Since(startTime)))
 }
 
-// Automatically tracks the result of a function
-func (m *SiMetrics) TrackFuncInt(name string, f func() int) TrackingMetric {
-	ctx, ctxCancelFunc := context.WithCancel(m.ctx)
+// Automatically runs a function on every tracking period. Useful to report several metrics at once
+func (m *SiMetrics) TrackFunc(f func()) TrackingMetric {
+	return m.TrackFuncContext(context.Background(), f)
+}
+
+// Like `TrackFunc`, but also stops when the parent `ctx` is done
+// Example usage, tracking a job while it runs:
+// ```
+// metric.TrackFuncContext(jobCtx, func() { metric.Value("job.progress", job.Progress()) })
+// ```
+func (m *SiMetrics) TrackFuncContext(ctx context.Context, f func()) TrackingMetric {
+	trackCtx, ctxCancelFunc := context.WithCancel(m.ctx)
 
 	go func() {
 		for {
 			select {
+			case <-trackCtx.Done():
+				return
 			case <-ctx.Done():
+				ctxCancelFunc()
 				return
-			case <-time.After(m.opts.TrackVarsPeriod):
-				m.Value(name, float64(f()))
+			case <-time.After(m.state.Load().trackVarsPeriod):
+				f()
 			}
 		}
 	}()
@@ -140,25 +283,116 @@ func (m *SiMetrics) TrackFuncInt(name string, f func() int) TrackingMetric {
 	return TrackingMetric(ctxCancelFunc)
 }
 
+// Automatically tracks the result of a function
+func (m *SiMetrics) TrackFuncInt(name string, f func() int) TrackingMetric {
+	return m.TrackFuncIntContext(context.Background(), name, f)
+}
+
+// Like `TrackFuncInt`, but also stops when the parent `ctx` is done
+// Example usage, tracking a value while a job runs:
+// ```
+// metric.TrackFuncIntContext(jobCtx, "job.pending_items", job.PendingItems)
+// ```
+func (m *SiMetrics) TrackFuncIntContext(ctx context.Context, name string, f func() int) TrackingMetric {
+	return m.TrackFuncContext(ctx, func() {
+		m.Value(name, float64(f()))
+	})
+}
+
 // Automatically tracks the result of a function
 func (m *SiMetrics) TrackFuncFloat(name string, f func() float64) TrackingMetric {
-	ctx, ctxCancelFunc := context.WithCancel(m.ctx)
+	return m.TrackFuncFloatContext(context.Background(), name, f)
+}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(m.opts.TrackVarsPeriod):
-				m.Value(name, f())
-			}
-		}
-	}()
+// Like `TrackFuncFloat`, but also stops when the parent `ctx` is done
+func (m *SiMetrics) TrackFuncFloatContext(ctx context.Context, name string, f func() float64) TrackingMetric {
+	return m.TrackFuncContext(ctx, func() {
+		m.Value(name, f())
+	})
+}
 
-	return TrackingMetric(ctxCancelFunc)
+// Automatically runs a health check and reports its status as a service check
+// Example usage:
+// ```
+// metric.TrackServiceCheck("db", func() (sink.ServiceCheckStatus, string) { return pingDB(db) })
+// ```
+func (m *SiMetrics) TrackServiceCheck(name string, f func() (sink.ServiceCheckStatus, string)) TrackingMetric {
+	return m.TrackServiceCheckContext(context.Background(), name, f)
+}
+
+// Like `TrackServiceCheck`, but also stops when the parent `ctx` is done
+func (m *SiMetrics) TrackServiceCheckContext(ctx context.Context, name string, f func() (sink.ServiceCheckStatus, string)) TrackingMetric {
+	return m.TrackFuncContext(ctx, func() {
+		status, message := f()
+		m.ServiceCheck(name, status, message)
+	})
+}
+
+// Returns a copy of the metrics aggregated in-process: the last completed interval, the current one and the totals
+// since the start. Only the sinks that aggregate in-process (Librato and stdout) support it, otherwise returns false.
+func (m *SiMetrics) Snapshot() (sink.Snapshots, bool) {
+	if ss, ok := sink.Find[sink.MetricsSinkSnapshots](m.state.Load().sink); ok {
+		return ss.Snapshot(), true
+	}
+	return sink.Snapshots{}, false
+}
+
+// Returns the number of metrics dropped by each filter rule (see `sink.MetricsSinkFilter`), or false if the sink
+// isn't filtered
+func (m *SiMetrics) DroppedMetrics() (map[string]uint64, bool) {
+	if msf, ok := sink.Find[*sink.MetricsSinkFilter](m.state.Load().sink); ok {
+		return msf.Dropped(), true
+	}
+	return nil, false
 }
 
 // Stops all running tracking metrics
 func (m *SiMetrics) StopAllTrackingMetrics() {
 	m.ctxCancelFunc()
 }
+
+// Returns the bucket bounds of the longest matching prefix in `HistogramBuckets`, or nil
+func (m *SiMetrics) histogramBounds(name string) []float64 {
+	var bounds []float64
+	longestPrefix := -1
+	for prefix, b := range m.opts.HistogramBuckets {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longestPrefix {
+			bounds, longestPrefix = b, len(prefix)
+		}
+	}
+	return bounds
+}
+
+func (m *SiMetrics) inTimeUnit(d time.Duration) float64 {
+	return float64(d) / float64(m.state.Load().timeUnit)
+}
+
+// Returns the current sink and namespace. The sink isn't closed by a reload until `release` is called.
+func (m *SiMetrics) current() (ms sink.MetricsSink, namespace string, release func()) {
+	s := m.state.acquire()
+	return s.sink, s.namespace + m.prefix, s.release
+}
+
+func (m *SiMetrics) reportCount(ms sink.MetricsSink, name string, value float64) {
+	if ts, ok := ms.(sink.MetricsSinkTagged); ok && len(m.tags) > 0 {
+		ts.ReportCountTagged(name, value, m.tags)
+	} else {
+		ms.ReportCount(name, value)
+	}
+}
+
+func (m *SiMetrics) reportValue(ms sink.MetricsSink, name string, value float64) {
+	if ts, ok := ms.(sink.MetricsSinkTagged); ok && len(m.tags) > 0 {
+		ts.ReportValueTagged(name, value, m.tags)
+	} else {
+		ms.ReportValue(name, value)
+	}
+}
+
+func (m *SiMetrics) reportDistribution(ms sink.MetricsSink, name string, value float64) {
+	if ts, ok := ms.(sink.MetricsSinkTagged); ok && len(m.tags) > 0 {
+		ts.ReportDistributionTagged(name, value, m.tags)
+	} else {
+		ms.ReportDistribution(name, value)
+	}
+}
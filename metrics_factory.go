@@ -1,22 +1,30 @@
 package simetrics
 
 import (
-	"github.com/sirupsen/logrus"
-
 	"github.com/luismfonseca/simetrics/simetricsconfig"
 	"github.com/luismfonseca/simetrics/sink"
 )
 
 // Factory method to build `SiMetrics` from a config
-func FromConfig(conf *simetricsconfig.Config, log *logrus.Entry) *SiMetrics {
-	mOpts := MetricsOptions{TrackVarsPeriod: conf.TrackVarsPeriod, NamespaceFormat: conf.NamespaceFormat}
+// Example usage, with logrus or log/slog:
+// ```
+// metric := simetrics.FromConfig(conf, sink.NewLogrusLogger(log))
+// metric := simetrics.FromConfig(conf, sink.NewSlogLogger(slog.Default()))
+// ```
+func FromConfig(conf *simetricsconfig.Config, log sink.Logger) *SiMetrics {
+	mOpts := MetricsOptions{
+		TrackVarsPeriod: conf.TrackVarsPeriod,
+		NamespaceFormat: conf.NamespaceFormat,
+		TimeUnit:        conf.TimeUnit,
+	}
 	mBuilder := NewBuilder(mOpts, sink.FromConfig(conf, log))
 
 	m, err := mBuilder.Build()
 	if err != nil {
 		log.WithError(err).Warn("Failed to init the metrics. Not sending any metrics...")
-		return NewEmpty()
+		m = NewEmpty()
 	}
+	m.log = log // used on reloads
 
 	return m
 }
@@ -2,6 +2,7 @@ package simetrics
 
 import (
 	"math"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -81,6 +82,54 @@ func (msm MetricsSinkMock) ReportDistribution(name string, value float64) {
 	msm.Called(name, value)
 }
 
+func (msm *MetricsSinkMock) OnReportSet(name string, member string, tags []string) *mock.Call {
+	return msm.On("ReportSet", name, member, tags)
+}
+
+func (msm MetricsSinkMock) ReportSet(name string, member string, tags []string) {
+	msm.Called(name, member, tags)
+}
+
+func (msm *MetricsSinkMock) OnReportCountTagged(name string, value float64, tags []string) *mock.Call {
+	return msm.On("ReportCountTagged", name, value, tags)
+}
+
+func (msm MetricsSinkMock) ReportCountTagged(name string, value float64, tags []string) {
+	msm.Called(name, value, tags)
+}
+
+func (msm MetricsSinkMock) ReportValueTagged(name string, value float64, tags []string) {
+	msm.Called(name, value, tags)
+}
+
+func (msm MetricsSinkMock) ReportDistributionTagged(name string, value float64, tags []string) {
+	msm.Called(name, value, tags)
+}
+
+func (msm MetricsSinkMock) ReportEvent(event sink.Event) {
+	msm.Called(event)
+}
+
+func (msm MetricsSinkMock) ReportServiceCheck(check sink.ServiceCheck) {
+	msm.Called(check)
+}
+
+func (msm MetricsSinkMock) ReportHistogram(name string, value float64, bounds []float64, tags []string) {
+	msm.Called(name, value, bounds, tags)
+}
+
+func (msm MetricsSinkMock) ReportHistogramSampled(name string, value float64, rate float64, bounds []float64, tags []string) {
+	msm.Called(name, value, rate, bounds, tags)
+}
+
+func (msm MetricsSinkMock) ReportCountSampled(name string, value float64, rate float64, tags []string) {
+	msm.Called(name, value, rate, tags)
+}
+
+func (msm MetricsSinkMock) ReportDistributionSampled(name string, value float64, rate float64, tags []string) {
+	msm.Called(name, value, rate, tags)
+}
+
 func TestMetricsBuilder(t *testing.T) {
 	Convey("A SiMetricsBuilder", t, func() {
 		Convey("should provide a way to instance it", func() {
@@ -145,6 +194,89 @@ func TestMetrics(t *testing.T) {
 			})
 		})
 
+		Convey("should forward the set members to the MetricSink", func() {
+			msMock.OnReportSet("users", "user-1", nil).Return().Once()
+			m.Set("users", "user-1")
+
+			Convey("with the SiMetrics tags", func() {
+				msMock.OnReportSet("users", "user-1", []string{"region:eu"}).Return().Once()
+				m.WithTags("region:eu").Set("users", "user-1")
+			})
+
+			Convey("except if the sink doesn't support sets", func() {
+				m, buildErr := NewBuilder(MetricsOptions{}, sink.MetricsSinkEmpty{}).Build()
+				So(buildErr, ShouldBeNil)
+				m.Set("users", "user-1")
+			})
+		})
+
+		Convey("should forward the events to the MetricSink", func() {
+			msMock.On("ReportEvent", sink.Event{Title: "Deploy", Text: "v1.2.3", Tags: []string{"env:prod"}, Priority: sink.EventPriorityNormal}).Return().Once()
+			m.Event("Deploy", "v1.2.3", []string{"env:prod"}, sink.EventPriorityNormal)
+
+			Convey("with the SiMetrics tags", func() {
+				msMock.On("ReportEvent", sink.Event{Title: "Reload", Tags: []string{"region:eu", "env:prod"}, Priority: sink.EventPriorityLow}).Return().Once()
+				m.WithTags("region:eu").Event("Reload", "", []string{"env:prod"}, sink.EventPriorityLow)
+			})
+
+			Convey("except if the sink doesn't support events", func() {
+				m, buildErr := NewBuilder(MetricsOptions{}, sink.MetricsSinkEmpty{}).Build()
+				So(buildErr, ShouldBeNil)
+				m.Event("Deploy", "v1.2.3", nil, sink.EventPriorityNormal)
+			})
+		})
+
+		Convey("should forward the histograms to the MetricSink, by the longest matching prefix", func() {
+			msMock := MetricsSinkMock{}
+			m, buildErr := NewBuilder(MetricsOptions{NamespaceFormat: "test."}, &msMock).
+				WithHistogramBuckets("http.", []float64{100, 200}).
+				WithHistogramBuckets("http.latency", []float64{10, 20}).
+				Build()
+			So(buildErr, ShouldBeNil)
+
+			msMock.OnReportDistribution("test.http.latency", 15).Return().Once()
+			msMock.On("ReportHistogram", "test.http.latency", 15.0, []float64{10, 20}, []string(nil)).Return().Once()
+			m.Distribution("http.latency", 15)
+
+			msMock.OnReportDistribution("test.http.size", 150).Return().Once()
+			msMock.On("ReportHistogram", "test.http.size", 150.0, []float64{100, 200}, []string(nil)).Return().Once()
+			m.Distribution("http.size", 150)
+
+			msMock.OnReportDistribution("test.db.latency", 15).Return().Once()
+			m.Distribution("db.latency", 15)
+
+			Convey("also when sampled", func() {
+				msMock.On("ReportDistributionSampled", "test.http.latency", 15.0, 0.5, []string(nil)).Return().Once()
+				msMock.On("ReportHistogramSampled", "test.http.latency", 15.0, 0.5, []float64{10, 20}, []string(nil)).Return().Once()
+				m.DistributionSampled("http.latency", 15, 0.5)
+				So(msMock.AssertExpectations(t), ShouldBeTrue)
+			})
+		})
+
+		Convey("should forward the sampled counts and distributions to the MetricSink", func() {
+			msMock.On("ReportCountSampled", "something", 1.0, 0.01, []string{"route:a"}).Return().Once()
+			m.WithTags("route:a").IncrementSampled("something", 0.01)
+
+			msMock.On("ReportDistributionSampled", "dist", 345.0, 0.5, []string(nil)).Return().Once()
+			m.DistributionSampled("dist", 345, 0.5)
+
+			Convey("unless the rate is 1", func() {
+				msMock.OnReportDistribution("dist", 345).Return().Once()
+				m.DistributionSampled("dist", 345, 1)
+			})
+
+			Convey("or sample and upweight them if the sink doesn't support it", func() {
+				mssl := MetricsSinkStoreLast{}
+				m, buildErr := NewBuilder(MetricsOptions{}, &mssl).Build()
+				So(buildErr, ShouldBeNil)
+
+				for mssl.GetLastCount() == 0 {
+					m.CountSampled("something", 3, 0.25)
+				}
+				So(mssl.GetLastCount(), ShouldEqual, 12)
+			})
+		})
+
 		Convey("should offer an Increment and Decrement that gets forwarded to the MetricSink", func() {
 			msMock.OnReportCount("something", 1).Return().Once()
 			msMock.OnReportCount("something", -1).Return().Once()
@@ -195,6 +327,33 @@ func TestMetrics(t *testing.T) {
 			})
 		})
 
+		Convey("should forward the service checks to the MetricSink", func() {
+			msMock.On("ReportServiceCheck", sink.ServiceCheck{Name: "db", Status: sink.ServiceCheckCritical, Message: "timeout"}).Return().Once()
+			m.ServiceCheck("db", sink.ServiceCheckCritical, "timeout")
+		})
+
+		Convey("should keep track of a health check", func() {
+			var status atomic.Int32 // sink.ServiceCheckOK, written by the test while read by the tracking
+			msMock.On("ReportServiceCheck", sink.ServiceCheck{Name: "db", Status: sink.ServiceCheckOK}).Return().Once()
+			msMock.On("ReportServiceCheck", sink.ServiceCheck{Name: "db", Status: sink.ServiceCheckWarning, Message: "slow"}).Return().Once()
+
+			tracking := m.TrackServiceCheck("db", func() (sink.ServiceCheckStatus, string) {
+				current := sink.ServiceCheckStatus(status.Load())
+				if current == sink.ServiceCheckOK {
+					return current, ""
+				}
+				return current, "slow"
+			})
+			<-time.After(50 * time.Millisecond) // give it a head-start
+
+			<-time.After(m.opts.TrackVarsPeriod)
+			status.Store(int32(sink.ServiceCheckWarning))
+			<-time.After(m.opts.TrackVarsPeriod)
+
+			tracking.Stop()
+			<-time.After(m.opts.TrackVarsPeriod)
+		})
+
 		Convey("should emit metrics namespaced", func() {
 			msMock := MetricsSinkMock{}
 			m, buildErr := NewBuilder(MetricsOptions{NamespaceFormat: "test."}, &msMock).Build()
@@ -225,5 +384,56 @@ func TestMetrics(t *testing.T) {
 			msMock.OnReportCount("test.new-prefix.something", 123).Return().Once()
 			m2.Count("something", 123)
 		})
+
+		Convey("should allow the creation of new metrics with tags", func() {
+			m2 := m.WithTags("route:a")
+			m3 := m2.WithTags("status:2xx")
+			m4 := m2.WithTags("status:5xx")
+
+			msMock.OnReportCount("something", 1).Return().Once()
+			m.Increment("something")
+
+			msMock.OnReportCountTagged("something", 1, []string{"route:a", "status:2xx"}).Return().Once()
+			m3.Increment("something")
+
+			msMock.OnReportCountTagged("something", 1, []string{"route:a", "status:5xx"}).Return().Once()
+			m4.Increment("something")
+
+			Convey("and fallback to untagged metrics if the sink doesn't support tags", func() {
+				m, buildErr := NewBuilder(MetricsOptions{}, &MetricsSinkStoreLast{}).Build()
+				So(buildErr, ShouldBeNil)
+
+				m.WithTags("route:a").Count("something", 4)
+				So(m.state.Load().sink.(*MetricsSinkStoreLast).GetLastCount(), ShouldEqual, 4)
+			})
+		})
+
+		Convey("should share the meters and windowed distributions", func() {
+			m.Meter("requests").Mark(2)
+			So(m.Meter("requests").Count(), ShouldEqual, 2)
+			So(m.WithTags("route:a").Meter("requests").Count(), ShouldEqual, 2)
+			So(m.WithNamespacePrefix("other.").Meter("requests").Count(), ShouldEqual, 0)
+
+			m.WindowedDistribution("latency", time.Minute).AddEntry(10)
+			So(m.WindowedDistribution("latency", time.Hour).Snapshot().N, ShouldEqual, 1)
+
+			Convey("and report them if enabled", func() {
+				msMock := MetricsSinkMock{}
+				m, buildErr := NewBuilder(MetricsOptions{TrackVarsPeriod: 100 * time.Millisecond, ReportMeters: true}, &msMock).Build()
+				So(buildErr, ShouldBeNil)
+
+				msMock.OnReportValue("requests.rate_1m", 0).Return().Once()
+				msMock.OnReportValue("requests.rate_5m", 0).Return().Once()
+				msMock.OnReportValue("requests.rate_15m", 0).Return().Once()
+				msMock.OnReportValue("latency.mean", 10).Return().Once()
+				msMock.OnReportValue("latency.max", 10).Return().Once()
+				msMock.OnReportValue("latency.count", 1).Return().Once()
+
+				m.Meter("requests").Mark(1)
+				m.WindowedDistribution("latency", time.Minute).AddEntry(10)
+				<-time.After(150 * time.Millisecond)
+				m.StopAllTrackingMetrics()
+			})
+		})
 	})
 }
@@ -0,0 +1,160 @@
+package simetrics
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luismfonseca/simetrics/simetricsconfig"
+	"github.com/luismfonseca/simetrics/sink"
+)
+
+// The sink and the options that can be changed at runtime, shared by all the SiMetrics derived from the same one
+type state struct {
+	atomic.Pointer[reloadable]
+	reloadMutex sync.Mutex
+}
+
+type reloadable struct {
+	sink            sink.MetricsSink
+	namespace       string // computed namespaceFormat + app name
+	trackVarsPeriod time.Duration
+	timeUnit        time.Duration
+	inFlight        sync.RWMutex // read-locked by the reports to the sink, so that it's only closed once they're done
+}
+
+func newState(ms sink.MetricsSink, options MetricsOptions) *state {
+	s := &state{}
+	s.Store(&reloadable{
+		sink:            ms,
+		namespace:       namespaceFromFormat(options.NamespaceFormat),
+		trackVarsPeriod: options.TrackVarsPeriod,
+		timeUnit:        options.TimeUnit,
+	})
+	return s
+}
+
+// Returns the current reloadable, read-locked until `release` is called so that its sink isn't closed meanwhile
+func (s *state) acquire() *reloadable {
+	for {
+		r := s.Load()
+		r.inFlight.RLock()
+		if s.Load() == r {
+			return r
+		}
+		// swapped before it was locked, so it may be closing already
+		r.inFlight.RUnlock()
+	}
+}
+
+func (r *reloadable) release() {
+	r.inFlight.RUnlock()
+}
+
+// Replaces the current reloadable with the one built by `next`, then closes the old sink once the reports in flight
+// to it are done
+func (s *state) swap(next func(current *reloadable) *reloadable) error {
+	s.reloadMutex.Lock()
+	old := s.Load()
+	s.Store(next(old))
+	s.reloadMutex.Unlock()
+
+	old.inFlight.Lock()
+	defer old.inFlight.Unlock()
+	return closeSink(old.sink)
+}
+
+func namespaceFromFormat(namespaceFormat string) string {
+	if strings.Contains(namespaceFormat, "%s") {
+		return fmt.Sprintf(namespaceFormat, path.Base(os.Args[0]))
+	}
+	return namespaceFormat
+}
+
+// Replaces the sink of this SiMetrics, and of all the ones derived from it, with a new one. The new sink is
+// initialized first, and the metrics buffered by the old one are flushed once the reports in flight to it are done
+// (if it implements `sink.MetricsSinkCloser`).
+func (m *SiMetrics) SwapSink(ms sink.MetricsSink) error {
+	if err := ms.Init(); err != nil {
+		return err
+	}
+
+	return m.state.swap(func(current *reloadable) *reloadable {
+		return &reloadable{
+			sink:            ms,
+			namespace:       current.namespace,
+			trackVarsPeriod: current.trackVarsPeriod,
+			timeUnit:        current.timeUnit,
+		}
+	})
+}
+
+// Applies a new config at runtime: the backend, the namespace format, the tracking period and the time unit. The
+// SiMetrics derived from this one and the running `TrackFunc*` keep working with the new config. The config is
+// validated first, and kept unchanged if it's invalid.
+// Example usage, e.g. to flip the metrics to stdout while debugging:
+// ```
+// err := metric.Reload(&simetricsconfig.Config{Backend: "stdout"})
+// ```
+func (m *SiMetrics) Reload(conf *simetricsconfig.Config) error {
+	ms, err := sink.New(conf, m.log)
+	if err != nil {
+		return err
+	}
+	if err := ms.Init(); err != nil {
+		return err
+	}
+
+	return m.state.swap(func(*reloadable) *reloadable {
+		return &reloadable{
+			sink:            ms,
+			namespace:       namespaceFromFormat(conf.NamespaceFormat),
+			trackVarsPeriod: conf.TrackVarsPeriod,
+			timeUnit:        conf.TimeUnit,
+		}
+	})
+}
+
+// Reloads the config from the file at `path` (see `simetricsconfig.Load`) whenever its content changes, checking it on
+// every tracking period. Invalid configs are logged and ignored, keeping the current one.
+// Example usage:
+// ```
+// defer metric.WatchConfigFile("/etc/service/metrics.yaml").Stop()
+// ```
+func (m *SiMetrics) WatchConfigFile(path string) TrackingMetric {
+	last, _ := os.ReadFile(path)
+
+	return m.TrackFunc(func() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			m.log.WithError(err).WithField("path", path).Warn("Failed to read the metrics config")
+			return
+		}
+		if bytes.Equal(data, last) {
+			return
+		}
+		last = data
+
+		conf, err := simetricsconfig.Load(path)
+		if err == nil {
+			err = m.Reload(conf)
+		}
+		if err != nil {
+			m.log.WithError(err).WithField("path", path).Error("Failed to reload the metrics config. Keeping the current one...")
+			return
+		}
+		m.log.WithField("path", path).Info("Reloaded the metrics config.")
+	})
+}
+
+func closeSink(ms sink.MetricsSink) error {
+	if closer, ok := ms.(sink.MetricsSinkCloser); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package simetrics
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luismfonseca/simetrics/simetricsconfig"
+	"github.com/luismfonseca/simetrics/sink"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Records the names of the reported counts and whether it was closed
+type MetricsSinkRecorder struct {
+	mutex  sync.Mutex
+	counts []string
+	closed bool
+}
+
+func (msr *MetricsSinkRecorder) Init() error { return nil }
+
+func (msr *MetricsSinkRecorder) ReportCount(name string, value float64) {
+	msr.mutex.Lock()
+	defer msr.mutex.Unlock()
+	msr.counts = append(msr.counts, name)
+}
+
+func (msr *MetricsSinkRecorder) ReportValue(name string, value float64)        {}
+func (msr *MetricsSinkRecorder) ReportDistribution(name string, value float64) {}
+
+func (msr *MetricsSinkRecorder) Close() error {
+	msr.mutex.Lock()
+	defer msr.mutex.Unlock()
+	msr.closed = true
+	return nil
+}
+
+func (msr *MetricsSinkRecorder) Counts() []string {
+	msr.mutex.Lock()
+	defer msr.mutex.Unlock()
+	return append([]string{}, msr.counts...)
+}
+
+func (msr *MetricsSinkRecorder) Closed() bool {
+	msr.mutex.Lock()
+	defer msr.mutex.Unlock()
+	return msr.closed
+}
+
+// Blocks the reported counts until `unblock` is closed, signaling `entered` once they start
+type MetricsSinkBlocking struct {
+	MetricsSinkRecorder
+	entered chan struct{}
+	unblock chan struct{}
+}
+
+func (msb *MetricsSinkBlocking) ReportCount(name string, value float64) {
+	msb.entered <- struct{}{}
+	<-msb.unblock
+	msb.MetricsSinkRecorder.ReportCount(name, value)
+}
+
+func TestReload(t *testing.T) {
+	Convey("A SiMetrics", t, func() {
+		oldSink := &MetricsSinkRecorder{}
+		m, err := NewBuilder(MetricsOptions{NamespaceFormat: "app.", TrackVarsPeriod: time.Millisecond}, oldSink).Build()
+		So(err, ShouldBeNil)
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+		m.log = sink.NewLogrusLogger(logrus.NewEntry(logger))
+		Reset(m.StopAllTrackingMetrics)
+
+		derived := m.WithNamespacePrefix("db.")
+
+		Convey("should swap its sink, also for the derived instances, closing the old one", func() {
+			newSink := &MetricsSinkRecorder{}
+			So(m.SwapSink(newSink), ShouldBeNil)
+
+			m.Increment("requests")
+			derived.Increment("queries")
+
+			So(oldSink.Closed(), ShouldBeTrue)
+			So(oldSink.Counts(), ShouldBeEmpty)
+			So(newSink.Counts(), ShouldResemble, []string{"app.requests", "app.db.queries"})
+		})
+
+		Convey("should only close the old sink once the reports in flight to it are done", func() {
+			blocking := &MetricsSinkBlocking{entered: make(chan struct{}), unblock: make(chan struct{})}
+			So(m.SwapSink(blocking), ShouldBeNil)
+
+			go m.Increment("slow")
+			<-blocking.entered
+
+			swapped := make(chan error)
+			go func() { swapped <- m.SwapSink(&MetricsSinkRecorder{}) }()
+			select {
+			case <-swapped:
+				So("swapped before the report was done", ShouldBeEmpty)
+			case <-time.After(20 * time.Millisecond):
+			}
+			So(blocking.Closed(), ShouldBeFalse)
+
+			close(blocking.unblock)
+			So(<-swapped, ShouldBeNil)
+			So(blocking.Closed(), ShouldBeTrue)
+			So(blocking.Counts(), ShouldResemble, []string{"app.slow"})
+		})
+
+		Convey("should keep the old sink if the new one fails to init", func() {
+			So(m.SwapSink(MetricsSinkFailure{}), ShouldNotBeNil)
+
+			m.Increment("requests")
+			So(oldSink.Closed(), ShouldBeFalse)
+			So(oldSink.Counts(), ShouldResemble, []string{"app.requests"})
+		})
+
+		Convey("should keep running the tracking functions on the new sink", func() {
+			m.TrackFunc(func() { derived.Increment("tick") })
+
+			newSink := &MetricsSinkRecorder{}
+			So(m.SwapSink(newSink), ShouldBeNil)
+			<-time.After(50 * time.Millisecond)
+
+			So(newSink.Counts(), ShouldContain, "app.db.tick")
+		})
+
+		Convey("should reload a config", func() {
+			conf := &simetricsconfig.Config{Backend: "stdout", NamespaceFormat: "reloaded.", TimeUnit: time.Second}
+			So(m.Reload(conf), ShouldBeNil)
+
+			s := m.state.Load()
+			So(oldSink.Closed(), ShouldBeTrue)
+			So(s.sink, ShouldHaveSameTypeAs, &sink.MetricsSinkStdout{})
+			So(s.trackVarsPeriod, ShouldEqual, 5*time.Second)
+			So(m.inTimeUnit(2*time.Second), ShouldEqual, 2)
+			_, namespace, release := derived.current()
+			release()
+			So(namespace, ShouldEqual, "reloaded.db.")
+		})
+
+		Convey("should keep the current config if the new one is invalid", func() {
+			So(m.Reload(&simetricsconfig.Config{Backend: "libratto"}), ShouldNotBeNil)
+
+			So(oldSink.Closed(), ShouldBeFalse)
+			So(m.state.Load().sink, ShouldEqual, oldSink)
+		})
+
+		Convey("should reload the config when its file changes", func() {
+			path := filepath.Join(t.TempDir(), "metrics.yaml")
+			So(os.WriteFile(path, []byte("backend: none\nnamespace-format: app.\ntrack-vars-period: 1ms"), 0600), ShouldBeNil)
+
+			watcher := m.WatchConfigFile(path)
+			Reset(watcher.Stop)
+			<-time.After(20 * time.Millisecond)
+			So(m.state.Load().sink, ShouldEqual, oldSink)
+
+			So(os.WriteFile(path, []byte("backend: none\nnamespace-format: watched.\ntrack-vars-period: 1ms"), 0600), ShouldBeNil)
+			<-time.After(50 * time.Millisecond)
+
+			So(oldSink.Closed(), ShouldBeTrue)
+			_, namespace, release := m.current()
+			release()
+			So(namespace, ShouldEqual, "watched.")
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package simetricsconfig
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	builtinBackends  = []string{"librato", "dogstatsd", "stdout", "none", "empty"}
+	backendsMutex    sync.RWMutex
+	externalBackends = map[string]bool{}
+)
+
+// Declares a backend as known by `Validate`. It's called by `sink.Register`, so there's usually no need to call it.
+func RegisterBackend(name string) {
+	backendsMutex.Lock()
+	defer backendsMutex.Unlock()
+	externalBackends[name] = true
+}
+
+// Returns the names of the known backends, sorted
+func Backends() []string {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+
+	names := append([]string{}, builtinBackends...)
+	for name := range externalBackends {
+		if !isBuiltinBackend(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isBuiltinBackend(name string) bool {
+	for _, builtin := range builtinBackends {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+func isExternalBackend(name string) bool {
+	backendsMutex.RLock()
+	defer backendsMutex.RUnlock()
+	return externalBackends[name] && !isBuiltinBackend(name)
+}
+
+// Returns the raw config section of a backend, as given to its `sink.Factory`: the typed section of the built-in
+// backends (`librato` or `dogstatsd`), or its `options` otherwise. Returns an empty section if there is none.
+func (c *Config) Section(backend string) (map[string]interface{}, error) {
+	var typed interface{}
+	switch backend {
+	case "librato":
+		if c.Librato != nil {
+			typed = c.Librato
+		}
+	case "dogstatsd":
+		if c.DogStatsD != nil {
+			typed = c.DogStatsD
+		}
+	default:
+		if section, ok := c.Options[backend]; ok && section != nil {
+			return section, nil
+		}
+	}
+
+	section := map[string]interface{}{}
+	if typed != nil {
+		if err := mapstructure.Decode(typed, &section); err != nil {
+			return nil, err
+		}
+	}
+	return section, nil
+}
+
+// Decodes a raw config section into `out`, a pointer to a struct with `mapstructure` tags, the same way the config
+// files are decoded by `Load`: with durations such as `5s` and unknown keys reported as errors, with their field path.
+// Example usage, in a `sink.Factory`:
+// ```
+// var conf struct { Endpoint string `mapstructure:"endpoint"` }
+// err := simetricsconfig.DecodeSection(section, &conf)
+// ```
+func DecodeSection(section map[string]interface{}, out interface{}) error {
+	return decodeInto(section, out)
+}
@@ -16,10 +16,36 @@ type DogStatsDConfig struct {
 	SourceFormat string `mapstructure:"source-format"` // interpolated with the hostname
 }
 
+// The allow and deny rules of the metric names, as globs (e.g. `checkout.*`) or regular expressions between slashes
+// (e.g. `/^checkout\.(cart|payment)\./`)
+type FilterConfig struct {
+	Allow []string `mapstructure:"allow"` // only the matching metrics are kept, if set
+	Deny  []string `mapstructure:"deny"`
+}
+
+// A rewrite of the metrics whose name matches the regular expression `match`, using its captures (`$1` or `${name}`)
+// in the new name and tags
+type RelabelConfig struct {
+	Match    string   `mapstructure:"match"`
+	Name     string   `mapstructure:"name"`      // unchanged if empty
+	Tags     []string `mapstructure:"tags"`      // in the `key:value` format
+	DropTags []string `mapstructure:"drop-tags"` // by key
+}
+
 type Config struct {
 	Backend         string           `mapstructure:"backend"`
 	Librato         *LibratoConfig   `mapstructure:"librato"`
 	DogStatsD       *DogStatsDConfig `mapstructure:"dogstatsd"`
 	NamespaceFormat string           `mapstructure:"namespace-format"`
 	TrackVarsPeriod time.Duration    `mapstructure:"track-vars-period"` // defaults to 5s
+	TimeUnit        time.Duration    `mapstructure:"time-unit"`         // of the reported durations, defaults to 1ms
+
+	// The opaque config sections of the backends registered with `sink.Register`, by backend name
+	Options map[string]map[string]interface{} `mapstructure:"options"`
+
+	// The filters of the metrics sent to each backend, by backend name. They're applied after the relabeling.
+	Filters map[string]*FilterConfig `mapstructure:"filters"`
+
+	// The relabeling rules of the metrics sent to each backend, by backend name, applied in order
+	Relabel map[string][]RelabelConfig `mapstructure:"relabel"`
 }
@@ -0,0 +1,161 @@
+package simetricsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables overriding the config, e.g. `SIMETRICS_BACKEND` or
+// `SIMETRICS_LIBRATO_SOURCE_FORMAT` for `librato.source-format`
+const EnvPrefix = "SIMETRICS_"
+
+// Loads the config from a YAML (`.yaml`, `.yml`), JSON (`.json`) or TOML (`.toml`) file and applies the `SIMETRICS_*`
+// environment overrides on top. With an empty path, the config is loaded from the environment only.
+//
+// Durations are written as strings, such as `5s` or `1ms`. Unknown keys are reported as errors, so that typos don't
+// go unnoticed.
+//
+// Example usage:
+// ```
+// conf, err := simetricsconfig.Load("metrics.yaml") // SIMETRICS_BACKEND=stdout would override the file's backend
+// metric := simetrics.FromConfig(conf, log)
+// ```
+func Load(path string) (*Config, error) {
+	raw := map[string]interface{}{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if raw, err = unmarshalRaw(data, filepath.Ext(path)); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+
+	applyEnv(raw, reflect.TypeOf(Config{}), nil)
+
+	conf, err := decode(raw)
+	if err != nil {
+		if path != "" {
+			return nil, fmt.Errorf("decoding %s: %w", path, err)
+		}
+		return nil, err
+	}
+	return conf, nil
+}
+
+func unmarshalRaw(data []byte, ext string) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config format %q, expected .yaml, .yml, .json or .toml", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{} // e.g. an empty YAML file
+	}
+	return raw, nil
+}
+
+// Sets the keys of `raw` from the environment variables named after the `mapstructure` tags of `t`, creating the
+// nested sections as needed
+func applyEnv(raw map[string]interface{}, t reflect.Type, path []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		keyPath := append(path[:len(path):len(path)], key)
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			applyEnv(raw, fieldType, keyPath)
+			continue
+		}
+		if fieldType.Kind() == reflect.Map {
+			continue // opaque sections can't be overridden
+		}
+
+		value, ok := os.LookupEnv(envName(keyPath))
+		if !ok {
+			continue
+		}
+
+		section := raw
+		for _, k := range path {
+			nested, ok := section[k].(map[string]interface{})
+			if !ok {
+				nested = map[string]interface{}{}
+				section[k] = nested
+			}
+			section = nested
+		}
+		section[key] = value
+	}
+}
+
+func envName(keyPath []string) string {
+	name := strings.ToUpper(strings.Join(keyPath, "_"))
+	return EnvPrefix + strings.ReplaceAll(name, "-", "_")
+}
+
+func decode(raw map[string]interface{}) (*Config, error) {
+	conf := &Config{}
+	if err := decodeInto(raw, conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func decodeInto(raw map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			numericDurationHook,
+			mapstructure.StringToTimeDurationHookFunc(),
+		),
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(raw)
+}
+
+// Rejects plain numbers as durations, since `5` would otherwise silently mean 5ns
+func numericDurationHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(time.Duration(0)) {
+		return data, nil
+	}
+	switch from.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil, fmt.Errorf("expected a duration with a unit, such as \"%vs\", got %v", data, data)
+	}
+	return data, nil
+}
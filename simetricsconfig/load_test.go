@@ -0,0 +1,150 @@
+package simetricsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writeConfig(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	So(os.WriteFile(path, []byte(content), 0600), ShouldBeNil)
+	return path
+}
+
+// Sets the environment variable until the end of the current Convey scope
+func setEnv(key string, value string) {
+	So(os.Setenv(key, value), ShouldBeNil)
+	Reset(func() {
+		os.Unsetenv(key)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	Convey("Load", t, func() {
+		Convey("should read YAML files", func() {
+			conf, err := Load("testdata/config.yaml")
+
+			So(err, ShouldBeNil)
+			So(conf.Backend, ShouldEqual, "librato")
+			So(conf.NamespaceFormat, ShouldEqual, "{{.Env}}.api.")
+			So(conf.TrackVarsPeriod, ShouldEqual, 10*time.Second)
+			So(conf.TimeUnit, ShouldEqual, time.Microsecond)
+			So(conf.Librato, ShouldResemble, &LibratoConfig{Email: "metrics@example.com", Token: "secret", SourceFormat: "api-%s"})
+			So(conf.DogStatsD, ShouldBeNil)
+		})
+
+		Convey("should read JSON files", func() {
+			conf, err := Load("testdata/config.json")
+
+			So(err, ShouldBeNil)
+			So(conf.Backend, ShouldEqual, "dogstatsd")
+			So(conf.TrackVarsPeriod, ShouldEqual, time.Minute)
+			So(conf.DogStatsD, ShouldResemble, &DogStatsDConfig{Address: "127.0.0.1:8125", SourceFormat: "api-%s"})
+		})
+
+		Convey("should read TOML files", func() {
+			conf, err := Load("testdata/config.toml")
+
+			So(err, ShouldBeNil)
+			So(conf.Backend, ShouldEqual, "librato")
+			So(conf.TrackVarsPeriod, ShouldEqual, 30*time.Second)
+			So(conf.Librato.Namespace, ShouldEqual, "api")
+		})
+
+		Convey("should read the opaque backend options", func() {
+			conf, err := Load(writeConfig(t, "config.yaml", "backend: internal\noptions:\n  internal:\n    endpoint: https://example.com\n    timeout: 2s"))
+
+			So(err, ShouldBeNil)
+			section, err := conf.Section("internal")
+			So(err, ShouldBeNil)
+			So(section, ShouldResemble, map[string]interface{}{"endpoint": "https://example.com", "timeout": "2s"})
+
+			var internal struct {
+				Endpoint string        `mapstructure:"endpoint"`
+				Timeout  time.Duration `mapstructure:"timeout"`
+			}
+			So(DecodeSection(section, &internal), ShouldBeNil)
+			So(internal.Timeout, ShouldEqual, 2*time.Second)
+		})
+
+		Convey("should return the typed sections of the built-in backends", func() {
+			conf, err := Load("testdata/config.yaml")
+			So(err, ShouldBeNil)
+
+			section, err := conf.Section("librato")
+			So(err, ShouldBeNil)
+			So(section["token"], ShouldEqual, "secret")
+
+			section, err = conf.Section("dogstatsd")
+			So(err, ShouldBeNil)
+			So(section, ShouldBeEmpty)
+		})
+
+		Convey("should apply the environment overrides, also in nested sections", func() {
+			setEnv("SIMETRICS_BACKEND", "dogstatsd")
+			setEnv("SIMETRICS_TRACK_VARS_PERIOD", "2s")
+			setEnv("SIMETRICS_LIBRATO_TOKEN", "other-secret")
+			setEnv("SIMETRICS_DOGSTATSD_ADDRESS", "statsd:8125")
+
+			conf, err := Load("testdata/config.yaml")
+
+			So(err, ShouldBeNil)
+			So(conf.Backend, ShouldEqual, "dogstatsd")
+			So(conf.TrackVarsPeriod, ShouldEqual, 2*time.Second)
+			So(conf.Librato.Email, ShouldEqual, "metrics@example.com")
+			So(conf.Librato.Token, ShouldEqual, "other-secret")
+			So(conf.DogStatsD.Address, ShouldEqual, "statsd:8125")
+		})
+
+		Convey("should load from the environment only without a path", func() {
+			setEnv("SIMETRICS_BACKEND", "stdout")
+
+			conf, err := Load("")
+
+			So(err, ShouldBeNil)
+			So(conf.Backend, ShouldEqual, "stdout")
+			So(conf.Librato, ShouldBeNil)
+		})
+
+		Convey("should return errors with the field path", func() {
+			Convey("for invalid durations", func() {
+				_, err := Load(writeConfig(t, "config.yaml", "track-vars-period: 5 seconds"))
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "track-vars-period")
+			})
+
+			Convey("for durations without a unit", func() {
+				_, err := Load(writeConfig(t, "config.json", `{"time-unit": 1}`))
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "time-unit")
+			})
+
+			Convey("for unknown keys", func() {
+				_, err := Load(writeConfig(t, "config.toml", "[librato]\nemial = \"metrics@example.com\""))
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "emial")
+			})
+
+			Convey("for mistyped sections", func() {
+				_, err := Load(writeConfig(t, "config.yaml", "dogstatsd:\n  address: [a, b]"))
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "dogstatsd.address")
+			})
+		})
+
+		Convey("should reject unsupported formats", func() {
+			_, err := Load(writeConfig(t, "config.ini", "backend = librato"))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ".ini")
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package simetricsconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTrackVarsPeriod  = 5 * time.Second
+	DefaultTimeUnit         = time.Millisecond
+	DefaultSourceFormat     = "%s"
+	DefaultDogStatsDAddress = "127.0.0.1:8125"
+)
+
+// Checks the required fields of the chosen backend and applies the defaults of the unset fields, so that a
+// misconfiguration can fail fast at startup instead of silently disabling the metrics. The defaults are:
+//   - `track-vars-period`: 5s
+//   - `time-unit`: 1ms
+//   - `namespace-format`: none
+//   - `librato.source-format` and `dogstatsd.source-format`: `%s`, the hostname
+//   - `dogstatsd.address`: `127.0.0.1:8125`, the local agent
+//
+// Every problem found is returned, joined in a single error.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.TrackVarsPeriod < 0 {
+		errs = append(errs, fmt.Errorf("track-vars-period must be positive, got %s", c.TrackVarsPeriod))
+	} else if c.TrackVarsPeriod == 0 {
+		c.TrackVarsPeriod = DefaultTrackVarsPeriod
+	}
+
+	if c.TimeUnit < 0 {
+		errs = append(errs, fmt.Errorf("time-unit must be positive, got %s", c.TimeUnit))
+	} else if c.TimeUnit == 0 {
+		c.TimeUnit = DefaultTimeUnit
+	}
+
+	if err := checkFormat(c.NamespaceFormat); err != nil {
+		errs = append(errs, fmt.Errorf("namespace-format: %w", err))
+	}
+
+	switch c.Backend {
+	case "librato":
+		if c.Librato == nil {
+			errs = append(errs, errors.New("librato: section is required for the librato backend"))
+			break
+		}
+		if c.Librato.Email == "" {
+			errs = append(errs, errors.New("librato.email is required for the librato backend"))
+		}
+		if c.Librato.Token == "" {
+			errs = append(errs, errors.New("librato.token is required for the librato backend"))
+		}
+		if c.Librato.SourceFormat == "" {
+			c.Librato.SourceFormat = DefaultSourceFormat
+		} else if err := checkFormat(c.Librato.SourceFormat); err != nil {
+			errs = append(errs, fmt.Errorf("librato.source-format: %w", err))
+		}
+	case "dogstatsd":
+		if c.DogStatsD == nil {
+			c.DogStatsD = &DogStatsDConfig{}
+		}
+		if c.DogStatsD.Address == "" {
+			c.DogStatsD.Address = DefaultDogStatsDAddress
+		}
+		if c.DogStatsD.SourceFormat == "" {
+			c.DogStatsD.SourceFormat = DefaultSourceFormat
+		} else if err := checkFormat(c.DogStatsD.SourceFormat); err != nil {
+			errs = append(errs, fmt.Errorf("dogstatsd.source-format: %w", err))
+		}
+	case "stdout", "none", "empty":
+	case "":
+		errs = append(errs, fmt.Errorf("backend is required, one of %s", strings.Join(Backends(), ", ")))
+	default:
+		if isExternalBackend(c.Backend) {
+			break // validated by its factory
+		}
+		err := fmt.Errorf("unknown backend %q", c.Backend)
+		if suggestion := closest(c.Backend, Backends()); suggestion != "" {
+			err = fmt.Errorf("%w, did you mean %q?", err, suggestion)
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// The formats are interpolated with a single string (the app name or the hostname), if they have a `%s`
+func checkFormat(format string) error {
+	verbs := strings.Count(format, "%") - 2*strings.Count(format, "%%")
+	if verbs > 1 || (verbs == 1 && !strings.Contains(format, "%s")) {
+		return fmt.Errorf("%q can only have a single %%s", format)
+	}
+	return nil
+}
+
+// Returns the candidate closest to `name`, if it's close enough to be a typo
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/2+1
+	for _, candidate := range candidates {
+		if distance := levenshtein(strings.ToLower(name), candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,93 @@
+package simetricsconfig
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("Validate", t, func() {
+		Convey("should apply the defaults", func() {
+			conf := &Config{Backend: "dogstatsd"}
+
+			So(conf.Validate(), ShouldBeNil)
+			So(conf.TrackVarsPeriod, ShouldEqual, 5*time.Second)
+			So(conf.TimeUnit, ShouldEqual, time.Millisecond)
+			So(conf.NamespaceFormat, ShouldEqual, "")
+			So(conf.DogStatsD, ShouldResemble, &DogStatsDConfig{Address: "127.0.0.1:8125", SourceFormat: "%s"})
+		})
+
+		Convey("should keep the set fields", func() {
+			conf := &Config{
+				Backend:         "librato",
+				Librato:         &LibratoConfig{Email: "metrics@example.com", Token: "secret", SourceFormat: "staging_%s"},
+				NamespaceFormat: "%s.",
+				TrackVarsPeriod: time.Minute,
+				TimeUnit:        time.Second,
+			}
+
+			So(conf.Validate(), ShouldBeNil)
+			So(conf.Librato.SourceFormat, ShouldEqual, "staging_%s")
+			So(conf.NamespaceFormat, ShouldEqual, "%s.")
+			So(conf.TrackVarsPeriod, ShouldEqual, time.Minute)
+			So(conf.TimeUnit, ShouldEqual, time.Second)
+		})
+
+		Convey("should require the librato section and credentials", func() {
+			err := (&Config{Backend: "librato"}).Validate()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "librato: section is required")
+
+			err = (&Config{Backend: "librato", Librato: &LibratoConfig{Email: "metrics@example.com"}}).Validate()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "librato.token is required")
+			So(err.Error(), ShouldNotContainSubstring, "librato.email")
+		})
+
+		Convey("should suggest the closest backend on typos", func() {
+			err := (&Config{Backend: "libratto"}).Validate()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, `unknown backend "libratto", did you mean "librato"?`)
+
+			err = (&Config{Backend: "DogStatsD"}).Validate()
+			So(err.Error(), ShouldContainSubstring, `did you mean "dogstatsd"?`)
+
+			err = (&Config{Backend: "prometheus"}).Validate()
+			So(err.Error(), ShouldEqual, `unknown backend "prometheus"`)
+		})
+
+		Convey("should accept the registered backends", func() {
+			RegisterBackend("test-backend")
+
+			So((&Config{Backend: "test-backend"}).Validate(), ShouldBeNil)
+			So(Backends(), ShouldContain, "test-backend")
+			So((&Config{Backend: "test-bakend"}).Validate().Error(), ShouldContainSubstring, `did you mean "test-backend"?`)
+		})
+
+		Convey("should require a backend", func() {
+			err := (&Config{}).Validate()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "backend is required")
+		})
+
+		Convey("should reject invalid periods and formats, reporting every problem", func() {
+			err := (&Config{
+				Backend:         "dogstatsd",
+				DogStatsD:       &DogStatsDConfig{SourceFormat: "%s-%s"},
+				NamespaceFormat: "%d.",
+				TrackVarsPeriod: -time.Second,
+			}).Validate()
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "track-vars-period must be positive")
+			So(err.Error(), ShouldContainSubstring, "namespace-format")
+			So(err.Error(), ShouldContainSubstring, "dogstatsd.source-format")
+		})
+
+		Convey("should accept escaped percent signs in the formats", func() {
+			So((&Config{Backend: "stdout", NamespaceFormat: "100%%.%s."}).Validate(), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,194 @@
+package sink
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luismfonseca/simetrics/type/cardinality"
+	"github.com/luismfonseca/simetrics/type/distribution"
+)
+
+// The metrics aggregated over an interval. It's a copy, so it can be freely read and modified.
+type Snapshot struct {
+	Start         time.Time                             `json:"start"`
+	End           time.Time                             `json:"end"` // zero while the interval is in progress
+	Counts        map[string]float64                    `json:"counts"`
+	Values        map[string]float64                    `json:"values"`
+	Distributions map[string]*distribution.Distribution `json:"distributions"`
+	Sets          map[string]float64                    `json:"sets"` // the (estimated) number of unique members
+	Histograms    map[string]*distribution.Histogram    `json:"histograms"`
+}
+
+type Snapshots struct {
+	Last       *Snapshot `json:"last"`       // the last completed interval, nil until the first one completes
+	Current    *Snapshot `json:"current"`    // the interval in progress
+	Cumulative *Snapshot `json:"cumulative"` // everything since the sink started, including the current interval
+}
+
+// Optionally implemented by sinks that aggregate the metrics in-process, allowing them to be queried
+type MetricsSinkSnapshots interface {
+	// Returns a copy of the aggregated metrics
+	Snapshot() Snapshots
+}
+
+// The metrics reported within an interval
+type interval struct {
+	start         time.Time
+	counts        map[string]float64
+	values        map[string]float64
+	distributions map[string]*distribution.Distribution
+	sets          map[string]*cardinality.Set
+	histograms    map[string]*distribution.Histogram
+}
+
+func newInterval(start time.Time) *interval {
+	return &interval{
+		start:         start,
+		counts:        map[string]float64{},
+		values:        map[string]float64{},
+		distributions: map[string]*distribution.Distribution{},
+		sets:          map[string]*cardinality.Set{},
+		histograms:    map[string]*distribution.Histogram{},
+	}
+}
+
+// Merges the metrics of a later interval into this one. Nothing is shared with `other`.
+func (i *interval) add(other *interval) {
+	for name, value := range other.counts {
+		i.counts[name] += value
+	}
+	for name, value := range other.values {
+		i.values[name] = value
+	}
+	for name, dist := range other.distributions {
+		if _, ok := i.distributions[name]; !ok {
+			i.distributions[name] = &distribution.Distribution{}
+		}
+		i.distributions[name].Add(dist)
+	}
+	for name, set := range other.sets {
+		if _, ok := i.sets[name]; !ok {
+			i.sets[name] = cardinality.New()
+		}
+		i.sets[name].Add(set)
+	}
+	for name, histogram := range other.histograms {
+		if _, ok := i.histograms[name]; !ok {
+			i.histograms[name] = distribution.NewHistogram(histogram.Bounds)
+		}
+		_ = i.histograms[name].Add(histogram) // the bounds of a metric don't change
+	}
+}
+
+func (i *interval) snapshot(end time.Time) *Snapshot {
+	copied := newInterval(i.start)
+	copied.add(i)
+
+	sets := map[string]float64{}
+	for name, set := range copied.sets {
+		sets[name] = set.Cardinality()
+	}
+
+	return &Snapshot{
+		Start:         i.start,
+		End:           end,
+		Counts:        copied.counts,
+		Values:        copied.values,
+		Distributions: copied.distributions,
+		Sets:          sets,
+		Histograms:    copied.histograms,
+	}
+}
+
+// Aggregates the reported metrics over intervals, keeping the cumulative totals and the last completed interval
+type aggregator struct {
+	mutex      sync.Mutex
+	current    *interval
+	cumulative *interval // up to the last completed interval
+	last       *interval // read-only once completed
+	lastEnd    time.Time
+}
+
+func newAggregator() *aggregator {
+	now := time.Now()
+	return &aggregator{current: newInterval(now), cumulative: newInterval(now)}
+}
+
+// Completes the current interval, returning it, and starts a new one
+func (a *aggregator) flush() *interval {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	completed := a.current
+	a.current = newInterval(now)
+	a.cumulative.add(completed)
+	a.last, a.lastEnd = completed, now
+
+	return completed
+}
+
+func (a *aggregator) Snapshot() Snapshots {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	cumulative := newInterval(a.cumulative.start)
+	cumulative.add(a.cumulative)
+	cumulative.add(a.current)
+
+	snapshots := Snapshots{Current: a.current.snapshot(time.Time{}), Cumulative: cumulative.snapshot(time.Time{})}
+	if a.last != nil {
+		snapshots.Last = a.last.snapshot(a.lastEnd)
+	}
+	return snapshots
+}
+
+func (a *aggregator) addCount(name string, value float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.current.counts[name] += value
+}
+
+func (a *aggregator) setValue(name string, value float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.current.values[name] = value
+}
+
+func (a *aggregator) addDistribution(name string, value float64, weight float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	dist, ok := a.current.distributions[name]
+	if !ok {
+		dist = &distribution.Distribution{}
+		a.current.distributions[name] = dist
+	}
+	dist.AddWeighted(value, weight)
+}
+
+func (a *aggregator) addSetMember(name string, member string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	set, ok := a.current.sets[name]
+	if ok {
+		set.AddMember(member)
+	} else {
+		a.current.sets[name] = cardinality.FromMember(member)
+	}
+}
+
+func (a *aggregator) addHistogram(name string, value float64, bounds []float64, weight float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	histogram, ok := a.current.histograms[name]
+	if !ok {
+		histogram = distribution.NewHistogram(bounds)
+		a.current.histograms[name] = histogram
+	}
+	histogram.AddWeighted(value, weight)
+}
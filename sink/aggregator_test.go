@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAggregator(t *testing.T) {
+	Convey("An aggregator", t, func() {
+		a := newAggregator()
+		a.addCount("requests", 2)
+		a.setValue("connections", 5)
+		a.addDistribution("latency", 10, 1)
+		a.addSetMember("users", "a")
+		a.addHistogram("size", 150, []float64{100, 200}, 1)
+
+		Convey("should snapshot the current interval", func() {
+			snapshots := a.Snapshot()
+
+			So(snapshots.Last, ShouldBeNil)
+			So(snapshots.Current.End.IsZero(), ShouldBeTrue)
+			So(snapshots.Current.Counts["requests"], ShouldEqual, 2)
+			So(snapshots.Current.Values["connections"], ShouldEqual, 5)
+			So(snapshots.Current.Distributions["latency"].N, ShouldEqual, 1)
+			So(snapshots.Current.Sets["users"], ShouldEqual, 1)
+			So(snapshots.Current.Histograms["size"].Counts, ShouldResemble, []float64{0, 1, 0})
+			So(snapshots.Cumulative, ShouldResemble, snapshots.Current)
+		})
+
+		Convey("should keep the last completed interval and the cumulative totals", func() {
+			completed := a.flush()
+			So(completed.counts["requests"], ShouldEqual, 2)
+
+			a.addCount("requests", 3)
+			a.setValue("connections", 4)
+			a.addDistribution("latency", 20, 1)
+			a.addSetMember("users", "b")
+
+			snapshots := a.Snapshot()
+			So(snapshots.Last.End.IsZero(), ShouldBeFalse)
+			So(snapshots.Last.Counts["requests"], ShouldEqual, 2)
+			So(snapshots.Current.Counts["requests"], ShouldEqual, 3)
+			So(snapshots.Current.Histograms, ShouldBeEmpty)
+			So(snapshots.Cumulative.Counts["requests"], ShouldEqual, 5)
+			So(snapshots.Cumulative.Values["connections"], ShouldEqual, 4)
+			So(snapshots.Cumulative.Distributions["latency"].Mean(), ShouldEqual, 15)
+			So(snapshots.Cumulative.Sets["users"], ShouldEqual, 2)
+			So(snapshots.Cumulative.Histograms["size"].Counts, ShouldResemble, []float64{0, 1, 0})
+		})
+
+		Convey("should return copies", func() {
+			snapshots := a.Snapshot()
+			snapshots.Current.Counts["requests"] = 100
+			snapshots.Current.Distributions["latency"].AddEntry(1000)
+
+			snapshots = a.Snapshot()
+			So(snapshots.Current.Counts["requests"], ShouldEqual, 2)
+			So(snapshots.Current.Distributions["latency"].Max, ShouldEqual, 10)
+		})
+	})
+}
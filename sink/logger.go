@@ -0,0 +1,70 @@
+package sink
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The logger of the sinks, with adapters for logrus (`NewLogrusLogger`) and log/slog (`NewSlogLogger`)
+type Logger interface {
+	// Returns a logger adding the field to every entry
+	WithField(key string, value interface{}) Logger
+
+	// Returns a logger adding the error to every entry
+	WithError(err error) Logger
+
+	Debug(msg string)
+	Info(msg string)
+	Warn(msg string)
+	Error(msg string)
+}
+
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// Adapts a logrus entry to a `Logger`
+func NewLogrusLogger(entry *logrus.Entry) Logger {
+	return logrusLogger{entry: entry}
+}
+
+func (ll logrusLogger) WithField(key string, value interface{}) Logger {
+	return logrusLogger{entry: ll.entry.WithField(key, value)}
+}
+
+func (ll logrusLogger) WithError(err error) Logger {
+	return logrusLogger{entry: ll.entry.WithError(err)}
+}
+
+func (ll logrusLogger) Debug(msg string) { ll.entry.Debug(msg) }
+func (ll logrusLogger) Info(msg string)  { ll.entry.Info(msg) }
+func (ll logrusLogger) Warn(msg string)  { ll.entry.Warn(msg) }
+func (ll logrusLogger) Error(msg string) { ll.entry.Error(msg) }
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// Adapts a slog logger to a `Logger`. A nil `logger` uses `slog.Default()`.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return slogLogger{logger: logger}
+}
+
+func (sl slogLogger) WithField(key string, value interface{}) Logger {
+	return slogLogger{logger: sl.logger.With(key, value)}
+}
+
+// The error is added as the `error` attribute, like logrus does
+func (sl slogLogger) WithError(err error) Logger {
+	return slogLogger{logger: sl.logger.With(logrus.ErrorKey, err)}
+}
+
+func (sl slogLogger) Debug(msg string) { sl.logger.Log(context.Background(), slog.LevelDebug, msg) }
+func (sl slogLogger) Info(msg string)  { sl.logger.Log(context.Background(), slog.LevelInfo, msg) }
+func (sl slogLogger) Warn(msg string)  { sl.logger.Log(context.Background(), slog.LevelWarn, msg) }
+func (sl slogLogger) Error(msg string) { sl.logger.Log(context.Background(), slog.LevelError, msg) }
@@ -0,0 +1,44 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogger(t *testing.T) {
+	Convey("The logger adapters", t, func() {
+		var out bytes.Buffer
+
+		Convey("should log through logrus", func() {
+			logger := logrus.New()
+			logger.SetOutput(&out)
+			logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+
+			NewLogrusLogger(logrus.NewEntry(logger)).WithField("backend", "stdout").WithError(errors.New("boom")).Warn("Failed")
+			NewLogrusLogger(logrus.NewEntry(logger)).Debug("Not logged")
+
+			So(out.String(), ShouldEqual, "level=warning msg=Failed backend=stdout error=boom\n")
+		})
+
+		Convey("should log through slog", func() {
+			logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+
+			NewSlogLogger(logger).WithField("backend", "stdout").WithError(errors.New("boom")).Warn("Failed")
+			NewSlogLogger(logger).Debug("Not logged")
+
+			So(out.String(), ShouldEqual, "level=WARN msg=Failed backend=stdout error=boom\n")
+		})
+	})
+}
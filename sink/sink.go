@@ -1,5 +1,9 @@
 package sink
 
+import (
+	"math/rand"
+)
+
 type MetricsSink interface {
 	// Performs any necessary initialization, returning an error if something fails
 	Init() error
@@ -13,3 +17,117 @@ type MetricsSink interface {
 	// Reports another value for a distribution
 	ReportDistribution(name string, value float64)
 }
+
+// Optionally implemented by sinks that support tagging metrics. Tags are in the `key:value` format.
+// Sinks that don't implement it receive the untagged reports instead.
+type MetricsSinkTagged interface {
+	// Reports a count (this is a delta value) with the given tags
+	ReportCountTagged(name string, value float64, tags []string)
+
+	// Reports the current value with the given tags
+	ReportValueTagged(name string, value float64, tags []string)
+
+	// Reports another value for a distribution with the given tags
+	ReportDistributionTagged(name string, value float64, tags []string)
+}
+
+type EventPriority string
+
+const (
+	EventPriorityNormal EventPriority = "normal"
+	EventPriorityLow    EventPriority = "low"
+)
+
+// Something that happened, such as a deploy or a config reload, to be shown alongside the metrics
+type Event struct {
+	Title    string
+	Text     string
+	Tags     []string // in the `key:value` format
+	Priority EventPriority
+}
+
+// Optionally implemented by sinks that support events. Sinks that don't implement it ignore them.
+type MetricsSinkEvents interface {
+	// Reports an event
+	ReportEvent(event Event)
+}
+
+// The status of a service check, with the same values as the DogStatsD protocol
+type ServiceCheckStatus int
+
+const (
+	ServiceCheckOK ServiceCheckStatus = iota
+	ServiceCheckWarning
+	ServiceCheckCritical
+	ServiceCheckUnknown
+)
+
+func (s ServiceCheckStatus) String() string {
+	switch s {
+	case ServiceCheckOK:
+		return "OK"
+	case ServiceCheckWarning:
+		return "WARNING"
+	case ServiceCheckCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// The health status of a service or dependency
+type ServiceCheck struct {
+	Name    string
+	Status  ServiceCheckStatus
+	Message string
+	Tags    []string // in the `key:value` format
+}
+
+// Optionally implemented by sinks that support service checks. Sinks that don't implement it ignore them.
+type MetricsSinkServiceChecks interface {
+	// Reports the status of a service check
+	ReportServiceCheck(check ServiceCheck)
+}
+
+// Optionally implemented by sinks that support histograms with fixed buckets.
+// Sinks that don't implement it only receive the value as a distribution.
+type MetricsSinkHistograms interface {
+	// Reports another value for the histogram with the given bucket bounds. Sinks without tag support ignore the tags.
+	ReportHistogram(name string, value float64, bounds []float64, tags []string)
+}
+
+// Optionally implemented by sinks that support sets. Sinks that don't implement it ignore them.
+type MetricsSinkSets interface {
+	// Reports a member of a set, to count the unique members. Sinks without tag support ignore the tags.
+	ReportSet(name string, member string, tags []string)
+}
+
+// Optionally implemented by sinks that support both histograms and sampling. Sinks that don't implement it receive
+// the histogram reports already sampled.
+type MetricsSinkHistogramsSampled interface {
+	// Reports another value for the histogram with the given bucket bounds, sampled at `rate`
+	ReportHistogramSampled(name string, value float64, rate float64, bounds []float64, tags []string)
+}
+
+// Optionally implemented by sinks that support sampling. The sinks keep only a `rate` fraction of the reports and
+// account for the dropped ones, e.g. by upweighting the kept ones. Sinks without tag support ignore the tags.
+// Sinks that don't implement it receive the reports already sampled.
+type MetricsSinkSampled interface {
+	// Reports a count (this is a delta value), sampled at `rate`
+	ReportCountSampled(name string, value float64, rate float64, tags []string)
+
+	// Reports another value for a distribution, sampled at `rate`
+	ReportDistributionSampled(name string, value float64, rate float64, tags []string)
+}
+
+// Optionally implemented by sinks that buffer metrics or run in the background, so that they can be replaced, e.g.
+// on a config reload, without losing data
+type MetricsSinkCloser interface {
+	// Flushes the buffered metrics and stops the sink. It shouldn't be used after.
+	Close() error
+}
+
+// Returns true for a `rate` fraction of the calls
+func ShouldSample(rate float64) bool {
+	return rate >= 1 || rand.Float64() < rate
+}
@@ -4,48 +4,56 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
 	"github.com/luismfonseca/simetrics/type/distribution"
-	"github.com/sirupsen/logrus"
 )
 
 type MetricsSinkDogStatsD struct {
-	tags          []string // `source:` + defaults to hostname
-	context       context.Context
-	statsDClient  *statsd.Client
-	mutex         sync.Mutex
-	counts        map[string]float64
-	distributions map[string]*distribution.Distribution
-	log           *logrus.Entry
+	tags              []string // `source:` + defaults to hostname
+	context           context.Context
+	contextCancelFunc context.CancelFunc
+	statsDClient      *statsd.Client
+	mutex             sync.Mutex
+	counts            map[string]float64
+	distributions     map[string]*distribution.Distribution
+	log               Logger
 }
 
-func NewMetricsSinkDogStatsD(address, sourceFormat string, log *logrus.Entry) *MetricsSinkDogStatsD {
+// Creates a sink reporting to the StatsD agent at `address`. Fails if the client can't be set up, e.g. for a malformed
+// address, so that a bad config is rejected rather than taking the process down.
+func NewMetricsSinkDogStatsD(address, sourceFormat string, log Logger) (*MetricsSinkDogStatsD, error) {
+	log = log.WithField(LogField, "dogstatsd")
 	source := sourceFormat
 	if strings.Contains(sourceFormat, "%s") {
 		hostname, err := os.Hostname()
 		if err != nil {
-			log.WithError(err).Warnln("Failed to get the hostname to use as the metric Source. Proceeding with an empty string.")
+			log.WithError(err).Warn("Failed to get the hostname to use as the metric Source. Proceeding with an empty string.")
 		}
 		source = fmt.Sprintf(sourceFormat, hostname)
 	}
 
 	client, err := statsd.New(address)
 	if err != nil {
-		log.WithField("address", address).Fatalf("Could not setup statsD client")
+		return nil, fmt.Errorf("could not setup the StatsD client for address %q: %w", address, err)
 	}
 
+	ctx, ctxCancelFunc := context.WithCancel(context.Background())
+
 	return &MetricsSinkDogStatsD{
-		tags:          []string{"source:" + source},
-		context:       context.Background(),
-		statsDClient:  client,
-		counts:        map[string]float64{},
-		distributions: map[string]*distribution.Distribution{},
-		log:           log,
-	}
+		tags:              []string{"source:" + source},
+		context:           ctx,
+		contextCancelFunc: ctxCancelFunc,
+		statsDClient:      client,
+		counts:            map[string]float64{},
+		distributions:     map[string]*distribution.Distribution{},
+		log:               log,
+	}, nil
 }
 
 func (msl *MetricsSinkDogStatsD) run() {
@@ -57,10 +65,10 @@ func (msl *MetricsSinkDogStatsD) run() {
 		case <-ticker.C:
 			err := msl.statsDClient.Flush()
 			if err != nil {
-				msl.log.WithError(err).Warnln("Failed to post metrics")
+				msl.log.WithError(err).Warn("Failed to post metrics")
 			}
 		case <-msl.context.Done():
-			msl.log.Infoln("Terminating StatsD Sink.")
+			msl.log.Info("Terminating StatsD Sink.")
 			return
 		}
 	}
@@ -72,6 +80,12 @@ func (msl *MetricsSinkDogStatsD) Init() error {
 	return nil
 }
 
+// Flushes the buffered metrics and closes the StatsD client
+func (msl *MetricsSinkDogStatsD) Close() error {
+	msl.contextCancelFunc()
+	return msl.statsDClient.Close()
+}
+
 func (msl *MetricsSinkDogStatsD) ReportCount(name string, value float64) {
 	_ = msl.statsDClient.Count(name, int64(value), msl.tags, 1)
 }
@@ -83,3 +97,71 @@ func (msl *MetricsSinkDogStatsD) ReportValue(name string, value float64) {
 func (msl *MetricsSinkDogStatsD) ReportDistribution(name string, value float64) {
 	_ = msl.statsDClient.Distribution(name, value, msl.tags, 1)
 }
+
+func (msl *MetricsSinkDogStatsD) ReportSet(name string, member string, tags []string) {
+	_ = msl.statsDClient.Set(name, member, msl.withTags(tags), 1)
+}
+
+func (msl *MetricsSinkDogStatsD) ReportCountTagged(name string, value float64, tags []string) {
+	_ = msl.statsDClient.Count(name, int64(value), msl.withTags(tags), 1)
+}
+
+func (msl *MetricsSinkDogStatsD) ReportValueTagged(name string, value float64, tags []string) {
+	_ = msl.statsDClient.Gauge(name, value, msl.withTags(tags), 1)
+}
+
+func (msl *MetricsSinkDogStatsD) ReportDistributionTagged(name string, value float64, tags []string) {
+	_ = msl.statsDClient.Distribution(name, value, msl.withTags(tags), 1)
+}
+
+func (msl *MetricsSinkDogStatsD) ReportEvent(event Event) {
+	err := msl.statsDClient.Event(&statsd.Event{
+		Title:    event.Title,
+		Text:     event.Text,
+		Tags:     msl.withTags(event.Tags),
+		Priority: statsd.EventPriority(event.Priority),
+	})
+	if err != nil {
+		msl.log.WithError(err).WithField("title", event.Title).Warn("Failed to send event")
+	}
+}
+
+func (msl *MetricsSinkDogStatsD) ReportServiceCheck(check ServiceCheck) {
+	err := msl.statsDClient.ServiceCheck(&statsd.ServiceCheck{
+		Name:    check.Name,
+		Status:  statsd.ServiceCheckStatus(check.Status),
+		Message: check.Message,
+		Tags:    msl.withTags(check.Tags),
+	})
+	if err != nil {
+		msl.log.WithError(err).WithField("name", check.Name).Warn("Failed to send service check")
+	}
+}
+
+// Reports the histogram as a single `<name>.bucket` count, tagged with the upper bound of the bucket the value falls
+// in (e.g. `le:300`). The counts aren't cumulative, the ones of the lower buckets have to be summed for that.
+func (msl *MetricsSinkDogStatsD) ReportHistogram(name string, value float64, bounds []float64, tags []string) {
+	msl.ReportHistogramSampled(name, value, 1, bounds, tags)
+}
+
+// The bucket count is sampled by the StatsD client, and upweighted by the agent
+func (msl *MetricsSinkDogStatsD) ReportHistogramSampled(name string, value float64, rate float64, bounds []float64, tags []string) {
+	le := "le:+Inf"
+	if i := sort.SearchFloat64s(bounds, value); i < len(bounds) {
+		le = "le:" + strconv.FormatFloat(bounds[i], 'g', -1, 64)
+	}
+	_ = msl.statsDClient.Count(name+".bucket", 1, append(msl.withTags(tags), le), rate)
+}
+
+func (msl *MetricsSinkDogStatsD) ReportCountSampled(name string, value float64, rate float64, tags []string) {
+	_ = msl.statsDClient.Count(name, int64(value), msl.withTags(tags), rate)
+}
+
+func (msl *MetricsSinkDogStatsD) ReportDistributionSampled(name string, value float64, rate float64, tags []string) {
+	_ = msl.statsDClient.Distribution(name, value, msl.withTags(tags), rate)
+}
+
+// Returns the sink tags followed by the given ones, without modifying the sink tags
+func (msl *MetricsSinkDogStatsD) withTags(tags []string) []string {
+	return append(msl.tags[:len(msl.tags):len(msl.tags)], tags...)
+}
@@ -0,0 +1,46 @@
+package sink
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetricsSinkDogStatsD(t *testing.T) {
+	Convey("A MetricsSinkDogStatsD", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		Reset(func() { _ = conn.Close() })
+
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+		msd, err := NewMetricsSinkDogStatsD(conn.LocalAddr().String(), "test", NewLogrusLogger(logrus.NewEntry(logger)))
+		So(err, ShouldBeNil)
+		Reset(func() { _ = msd.Close() })
+
+		// Returns the packets sent by the sink, one per line
+		received := func() []string {
+			So(msd.statsDClient.Flush(), ShouldBeNil)
+			buf := make([]byte, 1024)
+			So(conn.SetReadDeadline(time.Now().Add(time.Second)), ShouldBeNil)
+			n, _, err := conn.ReadFrom(buf)
+			So(err, ShouldBeNil)
+			return strings.Split(strings.TrimSpace(string(buf[:n])), "\n")
+		}
+
+		Convey("should report a histogram value as a single count tagged with its bucket", func() {
+			msd.ReportHistogram("latency_ms", 150, []float64{100, 200, 300}, []string{"env:prod"})
+			So(received(), ShouldResemble, []string{"latency_ms.bucket:1|c|#source:test,env:prod,le:200"})
+
+			Convey("including the values above the last bound", func() {
+				msd.ReportHistogram("latency_ms", 500, []float64{100, 200, 300}, nil)
+				So(received(), ShouldResemble, []string{"latency_ms.bucket:1|c|#source:test,le:+Inf"})
+			})
+		})
+	})
+}
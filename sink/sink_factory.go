@@ -1,35 +1,142 @@
 package sink
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/luismfonseca/simetrics/simetricsconfig"
-	"github.com/sirupsen/logrus"
 )
 
-func FromConfig(config *simetricsconfig.Config, log *logrus.Entry) MetricsSink {
-	switch config.Backend {
-	case "librato":
-		log.WithField("backend", "librato").Info("Using 'librato' backend for metrics.")
-		return NewMetricsSinkLibrato(
-			config.Librato.Email,
-			config.Librato.Token,
-			config.Librato.Namespace,
-			config.Librato.SourceFormat,
-			log,
-		)
-	case "dogstatsd":
-		log.WithField("backend", "dogtatsd").Info("Using 'dogtatsd' backend for metrics.")
-		return NewMetricsSinkDogStatsD(
-			config.DogStatsD.Address,
-			config.DogStatsD.SourceFormat,
-			log,
-		)
-	case "stdout":
-		return NewMetricsSinkStdout(log)
-	case "none", "empty":
-		log.WithField("backend", config.Backend).Info("Metrics reporting is explicitly disabled.")
-		return &MetricsSinkEmpty{}
-	default:
-		log.WithField("backend", config.Backend).Warn("Undefined or unknown backend. Not sending any metrics...")
+// The field marking the log entries of the sinks, with the backend as value, e.g. so that the log hooks reporting
+// metrics can skip them. The sinks add it in their constructors, so that it's there however they're built.
+const LogField = "simetrics_backend"
+
+// Builds the sink of a backend from its raw config section (see `simetricsconfig.Config.Section`), which can be
+// decoded with `simetricsconfig.DecodeSection`. The sink should mark its log entries with `LogField`, like the
+// built-in ones do.
+type Factory func(section map[string]interface{}, log Logger) (MetricsSink, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{}
+)
+
+func init() {
+	Register("librato", newLibratoFromSection)
+	Register("dogstatsd", newDogStatsDFromSection)
+	Register("stdout", func(section map[string]interface{}, log Logger) (MetricsSink, error) {
+		return NewMetricsSinkStdout(log), nil
+	})
+	for _, name := range []string{"none", "empty"} {
+		Register(name, func(section map[string]interface{}, log Logger) (MetricsSink, error) {
+			log.WithField("backend", name).Info("Metrics reporting is explicitly disabled.")
+			return &MetricsSinkEmpty{}, nil
+		})
+	}
+}
+
+// Registers a backend, so that it can be chosen with `backend` in the config. Its factory receives the backend's
+// section of the config `options`. Like `sql.Register`, it panics if the factory is nil or the name is already
+// registered, so it's meant to be called on `init`.
+// Example usage:
+// ```
+// sink.Register("internal", func(section map[string]interface{}, log Logger) (sink.MetricsSink, error) { ... })
+// ```
+func Register(name string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if factory == nil {
+		panic("sink: Register factory is nil for backend " + name)
+	}
+	if _, ok := factories[name]; ok {
+		panic("sink: Register called twice for backend " + name)
+	}
+	factories[name] = factory
+	simetricsconfig.RegisterBackend(name)
+}
+
+// Builds the sink of the configured backend with its registered factory, after validating the config and applying
+// its defaults (see `simetricsconfig.Config.Validate`). The sink is wrapped with the backend's relabeling rules and
+// filters, if any.
+func New(config *simetricsconfig.Config, log Logger) (MetricsSink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	factoriesMutex.RLock()
+	factory, ok := factories[config.Backend]
+	factoriesMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", config.Backend)
+	}
+
+	section, err := config.Section(config.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", config.Backend, err)
+	}
+	ms, err := factory(section, log)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", config.Backend, err)
+	}
+
+	if filter := config.Filters[config.Backend]; filter != nil {
+		ms, err = NewMetricsSinkFilter(ms, FilterRules{Allow: filter.Allow, Deny: filter.Deny})
+		if err != nil {
+			return nil, fmt.Errorf("filters.%s.%w", config.Backend, err)
+		}
+	}
+
+	if relabel := config.Relabel[config.Backend]; len(relabel) > 0 {
+		rules := make([]RelabelRule, len(relabel))
+		for i, rule := range relabel {
+			rules[i] = RelabelRule{Match: rule.Match, Name: rule.Name, Tags: rule.Tags, DropTags: rule.DropTags}
+		}
+		ms, err = NewMetricsSinkRelabel(ms, rules)
+		if err != nil {
+			return nil, fmt.Errorf("relabel.%s: %w", config.Backend, err)
+		}
+	}
+	return ms, nil
+}
+
+// Like `New`, but an invalid config is logged and no metrics are sent
+func FromConfig(config *simetricsconfig.Config, log Logger) MetricsSink {
+	ms, err := New(config, log)
+	if err != nil {
+		log.WithError(err).Error("Invalid metrics config. Not sending any metrics...")
 		return &MetricsSinkEmpty{}
 	}
+	return ms
+}
+
+func newLibratoFromSection(section map[string]interface{}, log Logger) (MetricsSink, error) {
+	var conf simetricsconfig.LibratoConfig
+	if err := simetricsconfig.DecodeSection(section, &conf); err != nil {
+		return nil, err
+	}
+
+	log.WithField("backend", "librato").Info("Using 'librato' backend for metrics.")
+	return NewMetricsSinkLibrato(
+		conf.Email,
+		conf.Token,
+		conf.Namespace,
+		conf.SourceFormat,
+		log,
+	), nil
+}
+
+func newDogStatsDFromSection(section map[string]interface{}, log Logger) (MetricsSink, error) {
+	var conf simetricsconfig.DogStatsDConfig
+	if err := simetricsconfig.DecodeSection(section, &conf); err != nil {
+		return nil, err
+	}
+
+	ms, err := NewMetricsSinkDogStatsD(conf.Address, conf.SourceFormat, log)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("backend", "dogtatsd").Info("Using 'dogtatsd' backend for metrics.")
+	return ms, nil
 }
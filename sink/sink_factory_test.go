@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"io"
+	"testing"
+
+	"github.com/luismfonseca/simetrics/simetricsconfig"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFromConfig(t *testing.T) {
+	Convey("FromConfig", t, func() {
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+		log := NewLogrusLogger(logrus.NewEntry(logger))
+
+		Convey("should not send any metrics with an invalid config, instead of panicking", func() {
+			So(FromConfig(&simetricsconfig.Config{Backend: "librato"}, log), ShouldHaveSameTypeAs, &MetricsSinkEmpty{})
+			So(FromConfig(&simetricsconfig.Config{Backend: "libratto"}, log), ShouldHaveSameTypeAs, &MetricsSinkEmpty{})
+		})
+
+		Convey("should build the sink of the backend", func() {
+			So(FromConfig(&simetricsconfig.Config{Backend: "stdout"}, log), ShouldHaveSameTypeAs, &MetricsSinkStdout{})
+		})
+
+		Convey("should build the built-in sinks from their typed section", func() {
+			ms := FromConfig(&simetricsconfig.Config{
+				Backend: "librato",
+				Librato: &simetricsconfig.LibratoConfig{Email: "metrics@example.com", Token: "secret", SourceFormat: "staging"},
+			}, log)
+
+			So(ms, ShouldHaveSameTypeAs, &MetricsSinkLibrato{})
+			So(ms.(*MetricsSinkLibrato).Token, ShouldEqual, "secret")
+			So(ms.(*MetricsSinkLibrato).Source, ShouldEqual, "staging")
+		})
+
+		Convey("should reject a dogstatsd address the client can't use", func() {
+			_, err := New(&simetricsconfig.Config{
+				Backend:   "dogstatsd",
+				DogStatsD: &simetricsconfig.DogStatsDConfig{Address: "localhost:notaport"},
+			}, log)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "localhost:notaport")
+		})
+
+		Convey("should build the registered sinks from their options", func() {
+			_, err := New(&simetricsconfig.Config{
+				Backend: "test-registered",
+				Options: map[string]map[string]interface{}{"test-registered": {"endpoint": "https://example.com"}},
+			}, log)
+			So(err, ShouldBeNil)
+			So(registeredSection, ShouldResemble, map[string]interface{}{"endpoint": "https://example.com"})
+
+			Convey("and return their errors", func() {
+				_, err := New(&simetricsconfig.Config{
+					Backend: "test-registered",
+					Options: map[string]map[string]interface{}{"test-registered": {"endpiont": "https://example.com"}},
+				}, log)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "test-registered")
+				So(err.Error(), ShouldContainSubstring, "endpiont")
+			})
+		})
+
+		Convey("should not allow registering a backend twice", func() {
+			So(func() { Register("stdout", newDogStatsDFromSection) }, ShouldPanic)
+		})
+	})
+}
+
+var registeredSection map[string]interface{}
+
+func init() {
+	Register("test-registered", func(section map[string]interface{}, log Logger) (MetricsSink, error) {
+		var conf struct {
+			Endpoint string `mapstructure:"endpoint"`
+		}
+		if err := simetricsconfig.DecodeSection(section, &conf); err != nil {
+			return nil, err
+		}
+		registeredSection = section
+		return &MetricsSinkEmpty{}, nil
+	})
+}
@@ -0,0 +1,125 @@
+package sink
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// The key of `MetricsSinkFilter.Dropped` counting the metrics that don't match any allow rule
+const NotAllowed = "!allow"
+
+// The allow and deny rules of a `MetricsSinkFilter`. Each rule is either a glob, such as `checkout.*` (see
+// `path.Match`), or a regular expression between slashes, such as `/^checkout\.(cart|payment)\./`.
+type FilterRules struct {
+	// When set, only the metrics matching at least one of these are kept
+	Allow []string
+
+	// The metrics matching any of these are dropped, even if allowed
+	Deny []string
+}
+
+type filterRule struct {
+	pattern string
+	regexp  *regexp.Regexp // nil for globs
+	dropped atomic.Uint64
+}
+
+func newFilterRule(pattern string) (*filterRule, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return &filterRule{pattern: pattern, regexp: re}, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return &filterRule{pattern: pattern}, nil
+}
+
+func (fr *filterRule) matches(name string) bool {
+	if fr.regexp != nil {
+		return fr.regexp.MatchString(name)
+	}
+	matched, _ := path.Match(fr.pattern, name)
+	return matched
+}
+
+// A sink that drops the metrics by name, before they reach (and are aggregated by) the wrapped sink. The names are
+// matched after the namespace is added.
+type MetricsSinkFilter struct {
+	wrapper
+	allow      []*filterRule
+	deny       []*filterRule
+	notAllowed atomic.Uint64
+}
+
+// Wraps `next` with the filter `rules`, returning an error if any of them is invalid
+// Example usage:
+// ```
+// filtered, err := sink.NewMetricsSinkFilter(librato, sink.FilterRules{Allow: []string{"checkout.*"}, Deny: []string{`/\.debug$/`}})
+// ```
+func NewMetricsSinkFilter(next MetricsSink, rules FilterRules) (*MetricsSinkFilter, error) {
+	msf := &MetricsSinkFilter{}
+	msf.wrapper = wrapper{next: next, transform: msf.filter}
+
+	for i, pattern := range rules.Allow {
+		rule, err := newFilterRule(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("allow[%d]: %w", i, err)
+		}
+		msf.allow = append(msf.allow, rule)
+	}
+	for i, pattern := range rules.Deny {
+		rule, err := newFilterRule(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("deny[%d]: %w", i, err)
+		}
+		msf.deny = append(msf.deny, rule)
+	}
+
+	return msf, nil
+}
+
+func (msf *MetricsSinkFilter) filter(name string, tags []string) (string, []string, bool) {
+	if len(msf.allow) > 0 {
+		allowed := false
+		for _, rule := range msf.allow {
+			if rule.matches(name) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			msf.notAllowed.Add(1)
+			return name, tags, false
+		}
+	}
+
+	for _, rule := range msf.deny {
+		if rule.matches(name) {
+			rule.dropped.Add(1)
+			return name, tags, false
+		}
+	}
+
+	return name, tags, true
+}
+
+// Returns the number of reports dropped by each deny rule, by its pattern, and the ones not matching any allow rule,
+// under `NotAllowed`. Each call of a sampled report counts once.
+func (msf *MetricsSinkFilter) Dropped() map[string]uint64 {
+	dropped := map[string]uint64{}
+	if len(msf.allow) > 0 {
+		dropped[NotAllowed] = msf.notAllowed.Load()
+	}
+	for _, rule := range msf.deny {
+		dropped[rule.pattern] += rule.dropped.Load()
+	}
+	return dropped
+}
@@ -0,0 +1,121 @@
+package sink
+
+import (
+	"io"
+	"testing"
+
+	"github.com/luismfonseca/simetrics/simetricsconfig"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestStdout() *MetricsSinkStdout {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewMetricsSinkStdout(NewLogrusLogger(logrus.NewEntry(logger)))
+}
+
+func TestMetricsSinkFilter(t *testing.T) {
+	Convey("A MetricsSinkFilter", t, func() {
+		Convey("should keep the metrics by their allow and deny rules", func() {
+			cases := []struct {
+				rules FilterRules
+				name  string
+				kept  bool
+			}{
+				{FilterRules{}, "checkout.latency", true},
+				{FilterRules{Allow: []string{"checkout.*"}}, "checkout.latency", true},
+				{FilterRules{Allow: []string{"checkout.*"}}, "checkout.db.latency", true},
+				{FilterRules{Allow: []string{"checkout.*"}}, "search.latency", false},
+				{FilterRules{Allow: []string{"search.*", "checkout.*"}}, "search.latency", true},
+				{FilterRules{Deny: []string{"*.debug"}}, "checkout.debug", false},
+				{FilterRules{Deny: []string{"*.debug"}}, "checkout.latency", true},
+				{FilterRules{Allow: []string{"checkout.*"}, Deny: []string{"checkout.debug*"}}, "checkout.debug_info", false},
+				{FilterRules{Allow: []string{`/^(checkout|search)\./`}}, "search.latency", true},
+				{FilterRules{Allow: []string{`/^(checkout|search)\./`}}, "searches.latency", false},
+				{FilterRules{Deny: []string{`/\.[45]xx$/`}}, "checkout.error.4xx", false},
+				{FilterRules{Deny: []string{`/\.[45]xx$/`}}, "checkout.error.3xx", true},
+			}
+
+			for _, c := range cases {
+				stdout := newTestStdout()
+				msf, err := NewMetricsSinkFilter(stdout, c.rules)
+				So(err, ShouldBeNil)
+
+				msf.ReportCount(c.name, 1)
+				if c.kept {
+					So(stdout.Snapshot().Current.Counts, ShouldContainKey, c.name)
+				} else {
+					So(stdout.Snapshot().Current.Counts, ShouldBeEmpty)
+				}
+			}
+		})
+
+		Convey("should reject invalid rules", func() {
+			_, err := NewMetricsSinkFilter(&MetricsSinkEmpty{}, FilterRules{Deny: []string{"a.*", "/(/"}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "deny[1]:")
+
+			_, err = NewMetricsSinkFilter(&MetricsSinkEmpty{}, FilterRules{Allow: []string{"a.[b"}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "allow[0]:")
+		})
+
+		Convey("should count the dropped metrics by rule", func() {
+			msf, err := NewMetricsSinkFilter(&MetricsSinkEmpty{}, FilterRules{Allow: []string{"checkout.*"}, Deny: []string{"*.debug", "*.trace"}})
+			So(err, ShouldBeNil)
+
+			msf.ReportCount("search.latency", 1)
+			msf.ReportValue("checkout.debug", 1)
+			msf.ReportDistribution("checkout.debug", 1)
+			msf.ReportSet("checkout.users", "a", nil)
+
+			So(msf.Dropped(), ShouldResemble, map[string]uint64{NotAllowed: 1, "*.debug": 2, "*.trace": 0})
+		})
+
+		Convey("should keep the capabilities of the wrapped sink", func() {
+			stdout := newTestStdout()
+			msf, err := NewMetricsSinkFilter(stdout, FilterRules{Deny: []string{"*.debug"}})
+			So(err, ShouldBeNil)
+
+			msf.ReportCountTagged("checkout.requests", 2, []string{"tenant:a"})
+			msf.ReportHistogram("checkout.latency", 150, []float64{100, 200}, nil)
+			msf.ReportHistogram("checkout.debug", 150, []float64{100, 200}, nil)
+			msf.ReportDistributionSampled("checkout.size", 10, 1, nil)
+
+			snapshots, ok := Find[MetricsSinkSnapshots](msf)
+			So(ok, ShouldBeTrue)
+			current := snapshots.Snapshot().Current
+			So(current.Counts, ShouldContainKey, "checkout.requests|#tenant:a")
+			So(current.Histograms, ShouldContainKey, "checkout.latency")
+			So(current.Histograms, ShouldNotContainKey, "checkout.debug")
+			So(current.Distributions, ShouldContainKey, "checkout.size")
+
+			Convey("or fall back if it doesn't have them", func() {
+				_, ok := Find[MetricsSinkSnapshots](&MetricsSinkFilter{wrapper: wrapper{next: &MetricsSinkEmpty{}}})
+				So(ok, ShouldBeFalse)
+			})
+		})
+
+		Convey("should be built from the config of the backend", func() {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			log := NewLogrusLogger(logrus.NewEntry(logger))
+
+			ms, err := New(&simetricsconfig.Config{
+				Backend: "stdout",
+				Filters: map[string]*simetricsconfig.FilterConfig{"stdout": {Deny: []string{"*.debug"}}, "librato": {Deny: []string{"*"}}},
+			}, log)
+			So(err, ShouldBeNil)
+			So(ms, ShouldHaveSameTypeAs, &MetricsSinkFilter{})
+			So(ms.(*MetricsSinkFilter).Dropped(), ShouldContainKey, "*.debug")
+
+			_, err = New(&simetricsconfig.Config{
+				Backend: "stdout",
+				Filters: map[string]*simetricsconfig.FilterConfig{"stdout": {Allow: []string{"/(/"}}},
+			}, log)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "filters.stdout.allow[0]:")
+		})
+	})
+}
@@ -1,20 +1,24 @@
 package sink
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/heroku/go-metrics-librato"
-	"github.com/luismfonseca/simetrics/type/distribution"
-	"github.com/sirupsen/logrus"
 )
 
 const (
 	SubmitPeriodSeconds = 5
+
+	// Events are posted as annotations to the stream named after the namespace (or `simetrics` if there is none)
+	LibratoAnnotationsURL = "https://metrics-api.librato.com/v1/annotations/"
 )
 
 type MetricsSinkLibrato struct {
@@ -23,39 +27,42 @@ type MetricsSinkLibrato struct {
 	Namespace string
 	Source    string // defaults to hostname
 
-	context       context.Context
-	mutex         sync.Mutex
-	counts        map[string]float64
-	distributions map[string]*distribution.Distribution
-	log           *logrus.Entry
+	context           context.Context
+	contextCancelFunc context.CancelFunc
+	httpClient        *http.Client
+	*aggregator
+	log Logger
 }
 
-func NewMetricsSinkLibrato(email, token, namespace, sourceFormat string, log *logrus.Entry) *MetricsSinkLibrato {
+func NewMetricsSinkLibrato(email, token, namespace, sourceFormat string, log Logger) *MetricsSinkLibrato {
+	log = log.WithField(LogField, "librato")
 	source := sourceFormat
 	if strings.Contains(sourceFormat, "%s") {
 		hostname, err := os.Hostname()
 		if err != nil {
-			log.WithError(err).Warnln("Failed to get the hostname to use as the metric Source. Proceeding with an empty string.")
+			log.WithError(err).Warn("Failed to get the hostname to use as the metric Source. Proceeding with an empty string.")
 		}
 		source = fmt.Sprintf(sourceFormat, hostname)
 	}
 
+	ctx, ctxCancelFunc := context.WithCancel(context.Background())
+
 	return &MetricsSinkLibrato{
 		Email:     email,
 		Token:     token,
 		Namespace: namespace,
 		Source:    source,
 
-		context:       context.Background(),
-		counts:        map[string]float64{},
-		distributions: map[string]*distribution.Distribution{},
-		log:           log,
+		context:           ctx,
+		contextCancelFunc: ctxCancelFunc,
+		httpClient:        &http.Client{Timeout: SubmitPeriodSeconds * time.Second},
+		aggregator:        newAggregator(),
+		log:               log,
 	}
 }
 
 func (msl *MetricsSinkLibrato) buildBatch() librato.Batch {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
+	completed := msl.flush()
 
 	batch := librato.Batch{
 		// coerce timestamps to a stepping fn so that they line up in Librato graphs
@@ -65,80 +72,175 @@ func (msl *MetricsSinkLibrato) buildBatch() librato.Batch {
 		Counters:    make([]librato.Measurement, 0),
 	}
 
-	for name, value := range msl.counts {
-		batch.Gauges = append(batch.Gauges, librato.Measurement{
-			"name":  name,
-			"value": value,
-		})
+	for _, values := range []map[string]float64{completed.counts, completed.values} {
+		for name, value := range values {
+			batch.Gauges = append(batch.Gauges, librato.Measurement{
+				"name":  name,
+				"value": value,
+			})
+		}
 	}
-	for name, dist := range msl.distributions {
+	for name, dist := range completed.distributions {
 		batch.Gauges = append(batch.Gauges, librato.Measurement{
 			"name":        name,
 			"count":       dist.N,
 			"min":         dist.Min,
 			"max":         dist.Max,
 			"sum":         dist.SumX,
-			"sum_squares": dist.SumX2,
+			"sum_squares": dist.SumX2(),
 		})
 	}
-	msl.distributions = map[string]*distribution.Distribution{}
-	msl.counts = map[string]float64{}
+	for name, set := range completed.sets {
+		batch.Gauges = append(batch.Gauges, librato.Measurement{
+			"name":  name,
+			"value": set.Cardinality(),
+		})
+	}
+	for name, histogram := range completed.histograms {
+		for i, count := range histogram.CumulativeCounts() {
+			batch.Gauges = append(batch.Gauges, librato.Measurement{
+				"name":  bucketName(name, histogram.Bounds, i),
+				"value": count,
+			})
+		}
+	}
 
 	return batch
 }
 
 func (msl *MetricsSinkLibrato) run() {
-	ticker := time.Tick(SubmitPeriodSeconds * time.Second)
-	metricsApi := &librato.LibratoClient{Email: msl.Email, Token: msl.Token}
+	ticker := time.NewTicker(SubmitPeriodSeconds * time.Second)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker:
-			metricsBatch := msl.buildBatch()
-			msl.log.
-				WithField("batch_size", len(metricsBatch.Counters)+len(metricsBatch.Gauges)).
-				Debug("Posting librato metrics...")
-
-			err := metricsApi.PostMetrics(metricsBatch)
-			if err != nil {
-				msl.log.WithError(err).Warnln("Failed to post metrics")
+		case <-ticker.C:
+			if err := msl.postBatch(); err != nil {
+				msl.log.WithError(err).Warn("Failed to post metrics")
 			}
 
 		case <-msl.context.Done():
-			msl.log.Infoln("Terminating Librato Sink.")
+			msl.log.Info("Terminating Librato Sink.")
 			return
 		}
 	}
 }
 
+func (msl *MetricsSinkLibrato) postBatch() error {
+	metricsBatch := msl.buildBatch()
+	msl.log.
+		WithField("batch_size", len(metricsBatch.Counters)+len(metricsBatch.Gauges)).
+		Debug("Posting librato metrics...")
+
+	metricsApi := &librato.LibratoClient{Email: msl.Email, Token: msl.Token}
+	return metricsApi.PostMetrics(metricsBatch)
+}
+
 func (msl *MetricsSinkLibrato) Init() error {
 	go msl.run()
 
 	return nil
 }
 
-func (msl *MetricsSinkLibrato) ReportCount(name string, value float64) {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
+// Posts the metrics of the current interval and stops posting periodically
+func (msl *MetricsSinkLibrato) Close() error {
+	msl.contextCancelFunc()
+	return msl.postBatch()
+}
 
-	msl.counts[name] += value
+func (msl *MetricsSinkLibrato) ReportCount(name string, value float64) {
+	msl.addCount(name, value)
 }
 
 func (msl *MetricsSinkLibrato) ReportValue(name string, value float64) {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
-
-	msl.counts[name] = value
+	msl.setValue(name, value)
 }
 
 func (msl *MetricsSinkLibrato) ReportDistribution(name string, value float64) {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
+	msl.addDistribution(name, value, 1)
+}
+
+// Tags are not supported, so they are ignored
+func (msl *MetricsSinkLibrato) ReportCountSampled(name string, value float64, rate float64, tags []string) {
+	if ShouldSample(rate) {
+		msl.addCount(name, value/rate)
+	}
+}
+
+// Tags are not supported, so they are ignored
+func (msl *MetricsSinkLibrato) ReportDistributionSampled(name string, value float64, rate float64, tags []string) {
+	if ShouldSample(rate) {
+		msl.addDistribution(name, value, 1/rate)
+	}
+}
+
+// Tags are not supported, so they are ignored
+func (msl *MetricsSinkLibrato) ReportSet(name string, member string, tags []string) {
+	msl.addSetMember(name, member)
+}
+
+// Tags are not supported, so they are ignored
+func (msl *MetricsSinkLibrato) ReportHistogram(name string, value float64, bounds []float64, tags []string) {
+	msl.addHistogram(name, value, bounds, 1)
+}
+
+// Tags are not supported, so they are ignored
+func (msl *MetricsSinkLibrato) ReportHistogramSampled(name string, value float64, rate float64, bounds []float64, tags []string) {
+	if ShouldSample(rate) {
+		msl.addHistogram(name, value, bounds, 1/rate)
+	}
+}
+
+// Posts the event as an annotation, in the background
+func (msl *MetricsSinkLibrato) ReportEvent(event Event) {
+	stream := msl.Namespace
+	if stream == "" {
+		stream = "simetrics"
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"title":       event.Title,
+		"description": event.Text,
+		"source":      msl.Source,
+		"start_time":  time.Now().Unix(),
+	})
+	if err != nil {
+		msl.log.WithError(err).WithField("title", event.Title).Warn("Failed to encode event")
+		return
+	}
+
+	go func() {
+		err := msl.postAnnotation(stream, body)
+		if err != nil {
+			msl.log.WithError(err).WithField("title", event.Title).Warn("Failed to post event")
+		}
+	}()
+}
+
+func (msl *MetricsSinkLibrato) postAnnotation(stream string, body []byte) error {
+	req, err := http.NewRequest("POST", LibratoAnnotationsURL+stream, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(msl.Email, msl.Token)
+
+	resp, err := msl.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status posting annotation: %s", resp.Status)
+	}
+	return nil
+}
 
-	histogram, ok := msl.distributions[name]
-	if ok {
-		histogram.AddEntry(value)
-	} else {
-		msl.distributions[name] = distribution.FromValue(value)
+// Returns the name of the `i`th bucket of a histogram, e.g. `name.le_300` or `name.le_inf` for the last one
+func bucketName(name string, bounds []float64, i int) string {
+	if i >= len(bounds) {
+		return name + ".le_inf"
 	}
+	return name + ".le_" + strconv.FormatFloat(bounds[i], 'g', -1, 64)
 }
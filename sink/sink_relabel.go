@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A rewrite of the metrics whose name matches a regular expression. The captures, as `$1` or `${name}`, can be used
+// in the new name and tags.
+// Example usage, turning `product-a.new_device.error.4xx` into `new_device.error` with the tags `product:product-a`
+// and `status:4xx`:
+// ```
+// sink.RelabelRule{Match: `^([^.]+)\.(new_device\.error)\.(\dxx)$`, Name: "$2", Tags: []string{"product:$1", "status:$3"}}
+// ```
+type RelabelRule struct {
+	// The regular expression matched against the name, all the names if empty
+	Match string
+
+	// The new name, unchanged if empty
+	Name string
+
+	// The tags to add, in the `key:value` format. Tags expanded to an empty value are skipped.
+	Tags []string
+
+	// The keys of the tags to drop
+	DropTags []string
+}
+
+type relabelRule struct {
+	RelabelRule
+	regexp *regexp.Regexp
+}
+
+// Rewrites the names and tags of the metrics by a list of rules, applied in order, each one to the result of the
+// previous ones
+type Relabeler struct {
+	rules []relabelRule
+}
+
+func NewRelabeler(rules []RelabelRule) (*Relabeler, error) {
+	r := &Relabeler{}
+	for i, rule := range rules {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		r.rules = append(r.rules, relabelRule{RelabelRule: rule, regexp: re})
+	}
+	return r, nil
+}
+
+// Returns the rewritten name and tags. The given tags are never modified.
+func (r *Relabeler) Relabel(name string, tags []string) (string, []string) {
+	for _, rule := range r.rules {
+		match := rule.regexp.FindStringSubmatchIndex(name)
+		if match == nil {
+			continue
+		}
+
+		if len(rule.DropTags) > 0 {
+			kept := make([]string, 0, len(tags))
+			for _, tag := range tags {
+				if !containsString(rule.DropTags, tagKey(tag)) {
+					kept = append(kept, tag)
+				}
+			}
+			tags = kept
+		}
+
+		if len(rule.Tags) > 0 {
+			tags = tags[:len(tags):len(tags)]
+			for _, template := range rule.Tags {
+				tag := string(rule.regexp.ExpandString(nil, template, name, match))
+				if tag != "" && !strings.HasSuffix(tag, ":") {
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		if rule.Name != "" {
+			name = string(rule.regexp.ExpandString(nil, rule.Name, name, match))
+		}
+	}
+	return name, tags
+}
+
+func tagKey(tag string) string {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// A sink that rewrites the names and tags of the metrics before they reach the wrapped sink, e.g. to migrate from a
+// backend without tags to one with them
+type MetricsSinkRelabel struct {
+	wrapper
+	*Relabeler
+}
+
+// Wraps `next` with the relabeling `rules`, returning an error if any of them is invalid
+func NewMetricsSinkRelabel(next MetricsSink, rules []RelabelRule) (*MetricsSinkRelabel, error) {
+	relabeler, err := NewRelabeler(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	msr := &MetricsSinkRelabel{Relabeler: relabeler}
+	msr.wrapper = wrapper{next: next, transform: func(name string, tags []string) (string, []string, bool) {
+		name, tags = relabeler.Relabel(name, tags)
+		return name, tags, true
+	}}
+	return msr, nil
+}
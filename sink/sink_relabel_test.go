@@ -0,0 +1,144 @@
+package sink
+
+import (
+	"io"
+	"testing"
+
+	"github.com/luismfonseca/simetrics/simetricsconfig"
+	"github.com/sirupsen/logrus"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRelabeler(t *testing.T) {
+	Convey("A Relabeler", t, func() {
+		cases := []struct {
+			description string
+			rules       []RelabelRule
+			name        string
+			tags        []string
+			wantName    string
+			wantTags    []string
+		}{
+			{
+				description: "should keep the metrics without rules",
+				name:        "product-a.new_device.error.4xx",
+				wantName:    "product-a.new_device.error.4xx",
+			},
+			{
+				description: "should turn the name segments into tags",
+				rules: []RelabelRule{
+					{Match: `^([^.]+)\.(new_device\.error)\.(\dxx)$`, Name: "$2", Tags: []string{"product:$1", "status:$3"}},
+				},
+				name:     "product-a.new_device.error.4xx",
+				tags:     []string{"region:eu"},
+				wantName: "new_device.error",
+				wantTags: []string{"region:eu", "product:product-a", "status:4xx"},
+			},
+			{
+				description: "should support named captures",
+				rules: []RelabelRule{
+					{Match: `^(?P<product>[^.]+)\.(?P<metric>.+)$`, Name: "${metric}", Tags: []string{"product:${product}"}},
+				},
+				name:     "product-b.checkout.latency",
+				wantName: "checkout.latency",
+				wantTags: []string{"product:product-b"},
+			},
+			{
+				description: "should keep the metrics not matching",
+				rules: []RelabelRule{
+					{Match: `^product-a\.(.+)$`, Name: "$1", Tags: []string{"product:product-a"}},
+				},
+				name:     "product-b.checkout.latency",
+				tags:     []string{"region:eu"},
+				wantName: "product-b.checkout.latency",
+				wantTags: []string{"region:eu"},
+			},
+			{
+				description: "should skip the tags expanded to an empty value",
+				rules: []RelabelRule{
+					{Match: `^http\.(?:(\dxx)\.)?requests$`, Name: "http.requests", Tags: []string{"status:$1", "canary"}},
+				},
+				name:     "http.requests",
+				wantName: "http.requests",
+				wantTags: []string{"canary"},
+			},
+			{
+				description: "should drop tags by key",
+				rules: []RelabelRule{
+					{DropTags: []string{"user_id", "debug"}},
+				},
+				name:     "checkout.latency",
+				tags:     []string{"user_id:42", "region:eu", "debug"},
+				wantName: "checkout.latency",
+				wantTags: []string{"region:eu"},
+			},
+			{
+				description: "should apply the rules in order",
+				rules: []RelabelRule{
+					{Match: `^legacy\.(.+)$`, Name: "$1", Tags: []string{"legacy:true"}},
+					{Match: `^(.+)_ms$`, Name: "$1.latency", Tags: []string{"unit:ms"}},
+					{Match: `latency`, DropTags: []string{"legacy"}},
+				},
+				name:     "legacy.checkout_ms",
+				wantName: "checkout.latency",
+				wantTags: []string{"unit:ms"},
+			},
+		}
+
+		for _, c := range cases {
+			Convey(c.description, func() {
+				relabeler, err := NewRelabeler(c.rules)
+				So(err, ShouldBeNil)
+
+				tags := append([]string{}, c.tags...)
+				name, gotTags := relabeler.Relabel(c.name, tags)
+
+				So(name, ShouldEqual, c.wantName)
+				if len(c.wantTags) == 0 {
+					So(gotTags, ShouldBeEmpty)
+				} else {
+					So(gotTags, ShouldResemble, c.wantTags)
+				}
+				So(tags, ShouldResemble, append([]string{}, c.tags...)) // unmodified
+			})
+		}
+
+		Convey("should reject invalid rules", func() {
+			_, err := NewRelabeler([]RelabelRule{{Match: "a"}, {Match: "("}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "rules[1]:")
+		})
+	})
+
+	Convey("A MetricsSinkRelabel", t, func() {
+		Convey("should report the rewritten metrics to the wrapped sink", func() {
+			stdout := newTestStdout()
+			msr, err := NewMetricsSinkRelabel(stdout, []RelabelRule{{Match: `^([^.]+)\.(.+)$`, Name: "$2", Tags: []string{"product:$1"}}})
+			So(err, ShouldBeNil)
+
+			msr.ReportCount("product-a.requests", 1)
+			msr.ReportValueTagged("product-a.queue_size", 3, []string{"region:eu"})
+
+			current := stdout.Snapshot().Current
+			So(current.Counts, ShouldContainKey, "requests|#product:product-a")
+			So(current.Values, ShouldContainKey, "queue_size|#region:eu,product:product-a")
+		})
+
+		Convey("should be built from the config of the backend, before the filters", func() {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+
+			ms, err := New(&simetricsconfig.Config{
+				Backend: "stdout",
+				Relabel: map[string][]simetricsconfig.RelabelConfig{"stdout": {{Match: `^debug\.(.+)$`, Name: "$1.debug"}}},
+				Filters: map[string]*simetricsconfig.FilterConfig{"stdout": {Deny: []string{"*.debug"}}},
+			}, NewLogrusLogger(logrus.NewEntry(logger)))
+			So(err, ShouldBeNil)
+
+			ms.ReportCount("debug.requests", 1)
+			msf, ok := Find[*MetricsSinkFilter](ms)
+			So(ok, ShouldBeTrue)
+			So(msf.Dropped()["*.debug"], ShouldEqual, 1)
+		})
+	})
+}
@@ -1,11 +1,9 @@
 package sink
 
 import (
-	"sync"
+	"context"
+	"strings"
 	"time"
-
-	"github.com/luismfonseca/simetrics/type/distribution"
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -13,17 +11,21 @@ const (
 )
 
 type MetricsSinkStdout struct {
-	mutex         sync.Mutex
-	counts        map[string]float64
-	distributions map[string]*distribution.Distribution
-	log           *logrus.Entry
+	*aggregator
+	context           context.Context
+	contextCancelFunc context.CancelFunc
+	log               Logger
 }
 
-func NewMetricsSinkStdout(log *logrus.Entry) *MetricsSinkStdout {
+func NewMetricsSinkStdout(log Logger) *MetricsSinkStdout {
+	log = log.WithField(LogField, "stdout")
+	ctx, ctxCancelFunc := context.WithCancel(context.Background())
+
 	return &MetricsSinkStdout{
-		counts:        map[string]float64{},
-		distributions: map[string]*distribution.Distribution{},
-		log:           log,
+		aggregator:        newAggregator(),
+		context:           ctx,
+		contextCancelFunc: ctxCancelFunc,
+		log:               log,
 	}
 }
 
@@ -34,50 +36,118 @@ func (msl *MetricsSinkStdout) Init() error {
 }
 
 func (msl *MetricsSinkStdout) run() {
-	ticker := time.Tick(StdoutFlushPeriod)
+	ticker := time.NewTicker(StdoutFlushPeriod)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker:
-			msl.mutex.Lock()
-
-			for name, value := range msl.counts {
-				msl.log.WithField(name, value).Println("Metric report")
-			}
-			for name, dist := range msl.distributions {
-				msl.log.WithField(name, dist).Println("Metric report")
-			}
+		case <-ticker.C:
+			msl.logInterval()
+		case <-msl.context.Done():
+			return
+		}
+	}
+}
 
-			msl.distributions = map[string]*distribution.Distribution{}
-			msl.counts = map[string]float64{}
+func (msl *MetricsSinkStdout) logInterval() {
+	completed := msl.flush()
 
-			msl.mutex.Unlock()
-		}
+	for name, value := range completed.counts {
+		msl.log.WithField(name, value).Info("Metric report")
+	}
+	for name, value := range completed.values {
+		msl.log.WithField(name, value).Info("Metric report")
+	}
+	for name, dist := range completed.distributions {
+		msl.log.WithField(name, dist).Info("Metric report")
+	}
+	for name, set := range completed.sets {
+		msl.log.WithField(name, set.Cardinality()).Info("Metric report")
+	}
+	for name, histogram := range completed.histograms {
+		msl.log.WithField(name, histogram).Info("Metric report")
 	}
 }
 
-func (msl *MetricsSinkStdout) ReportCount(name string, value float64) {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
+// Logs the metrics of the current interval and stops logging periodically
+func (msl *MetricsSinkStdout) Close() error {
+	msl.contextCancelFunc()
+	msl.logInterval()
+	return nil
+}
 
-	msl.counts[name] += value
+func (msl *MetricsSinkStdout) ReportCount(name string, value float64) {
+	msl.addCount(name, value)
 }
 
 func (msl *MetricsSinkStdout) ReportValue(name string, value float64) {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
-
-	msl.counts[name] = value
+	msl.setValue(name, value)
 }
 
 func (msl *MetricsSinkStdout) ReportDistribution(name string, value float64) {
-	msl.mutex.Lock()
-	defer msl.mutex.Unlock()
-
-	histogram, ok := msl.distributions[name]
-	if ok {
-		histogram.AddEntry(value)
-	} else {
-		msl.distributions[name] = distribution.FromValue(value)
+	msl.addDistribution(name, value, 1)
+}
+
+func (msl *MetricsSinkStdout) ReportCountSampled(name string, value float64, rate float64, tags []string) {
+	if ShouldSample(rate) {
+		msl.addCount(taggedName(name, tags), value/rate)
+	}
+}
+
+func (msl *MetricsSinkStdout) ReportDistributionSampled(name string, value float64, rate float64, tags []string) {
+	if ShouldSample(rate) {
+		msl.addDistribution(taggedName(name, tags), value, 1/rate)
+	}
+}
+
+func (msl *MetricsSinkStdout) ReportSet(name string, member string, tags []string) {
+	msl.addSetMember(taggedName(name, tags), member)
+}
+
+func (msl *MetricsSinkStdout) ReportHistogram(name string, value float64, bounds []float64, tags []string) {
+	msl.addHistogram(taggedName(name, tags), value, bounds, 1)
+}
+
+func (msl *MetricsSinkStdout) ReportHistogramSampled(name string, value float64, rate float64, bounds []float64, tags []string) {
+	if ShouldSample(rate) {
+		msl.addHistogram(taggedName(name, tags), value, bounds, 1/rate)
+	}
+}
+
+func (msl *MetricsSinkStdout) ReportCountTagged(name string, value float64, tags []string) {
+	msl.ReportCount(taggedName(name, tags), value)
+}
+
+func (msl *MetricsSinkStdout) ReportValueTagged(name string, value float64, tags []string) {
+	msl.ReportValue(taggedName(name, tags), value)
+}
+
+func (msl *MetricsSinkStdout) ReportDistributionTagged(name string, value float64, tags []string) {
+	msl.ReportDistribution(taggedName(name, tags), value)
+}
+
+func (msl *MetricsSinkStdout) ReportEvent(event Event) {
+	msl.log.
+		WithField("title", event.Title).
+		WithField("text", event.Text).
+		WithField("tags", event.Tags).
+		WithField("priority", event.Priority).
+		Info("Event report")
+}
+
+func (msl *MetricsSinkStdout) ReportServiceCheck(check ServiceCheck) {
+	msl.log.
+		WithField("name", check.Name).
+		WithField("status", check.Status.String()).
+		WithField("message", check.Message).
+		WithField("tags", check.Tags).
+		Info("Service check report")
+}
+
+// Appends the tags to the name, the same way DogStatsD would show them, so that each tag combination is reported apart
+func taggedName(name string, tags []string) string {
+	if len(tags) == 0 {
+		return name
 	}
+	return name + "|#" + strings.Join(tags, ",")
 }
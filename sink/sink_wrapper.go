@@ -0,0 +1,159 @@
+package sink
+
+// Optionally implemented by the sinks wrapping another one, e.g. to filter the metrics, so that the capabilities of
+// the wrapped sink, such as `MetricsSinkSnapshots`, can still be reached
+type MetricsSinkWrapper interface {
+	// Returns the wrapped sink
+	Unwrap() MetricsSink
+}
+
+// Returns the first sink implementing `T`, going through the wrapped sinks
+func Find[T any](ms MetricsSink) (T, bool) {
+	for {
+		if found, ok := ms.(T); ok {
+			return found, true
+		}
+		w, ok := ms.(MetricsSinkWrapper)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		ms = w.Unwrap()
+	}
+}
+
+// Forwards the reports to the next sink with their names and tags transformed, or drops them if `transform` returns
+// false. The optional capabilities of the next sink are kept: the tags, sampling and histograms fall back the same way
+// `SiMetrics` would, and the sets, events and service checks are dropped if not supported.
+type wrapper struct {
+	next      MetricsSink
+	transform func(name string, tags []string) (string, []string, bool)
+}
+
+func (w *wrapper) Unwrap() MetricsSink {
+	return w.next
+}
+
+func (w *wrapper) Init() error {
+	return w.next.Init()
+}
+
+// Closes the next sink, if it supports it
+func (w *wrapper) Close() error {
+	if closer, ok := w.next.(MetricsSinkCloser); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (w *wrapper) ReportCount(name string, value float64) {
+	w.ReportCountTagged(name, value, nil)
+}
+
+func (w *wrapper) ReportValue(name string, value float64) {
+	w.ReportValueTagged(name, value, nil)
+}
+
+func (w *wrapper) ReportDistribution(name string, value float64) {
+	w.ReportDistributionTagged(name, value, nil)
+}
+
+func (w *wrapper) ReportCountTagged(name string, value float64, tags []string) {
+	if name, tags, ok := w.transform(name, tags); ok {
+		w.reportCount(name, value, tags)
+	}
+}
+
+func (w *wrapper) ReportValueTagged(name string, value float64, tags []string) {
+	if name, tags, ok := w.transform(name, tags); ok {
+		if ts, ok := w.next.(MetricsSinkTagged); ok && len(tags) > 0 {
+			ts.ReportValueTagged(name, value, tags)
+		} else {
+			w.next.ReportValue(name, value)
+		}
+	}
+}
+
+func (w *wrapper) ReportDistributionTagged(name string, value float64, tags []string) {
+	if name, tags, ok := w.transform(name, tags); ok {
+		w.reportDistribution(name, value, tags)
+	}
+}
+
+func (w *wrapper) ReportSet(name string, member string, tags []string) {
+	if ss, ok := w.next.(MetricsSinkSets); ok {
+		if name, tags, ok := w.transform(name, tags); ok {
+			ss.ReportSet(name, member, tags)
+		}
+	}
+}
+
+func (w *wrapper) ReportCountSampled(name string, value float64, rate float64, tags []string) {
+	if name, tags, ok := w.transform(name, tags); ok {
+		if ss, ok := w.next.(MetricsSinkSampled); ok {
+			ss.ReportCountSampled(name, value, rate, tags)
+		} else if ShouldSample(rate) {
+			w.reportCount(name, value/rate, tags)
+		}
+	}
+}
+
+func (w *wrapper) ReportDistributionSampled(name string, value float64, rate float64, tags []string) {
+	if name, tags, ok := w.transform(name, tags); ok {
+		if ss, ok := w.next.(MetricsSinkSampled); ok {
+			ss.ReportDistributionSampled(name, value, rate, tags)
+		} else if ShouldSample(rate) {
+			w.reportDistribution(name, value, tags)
+		}
+	}
+}
+
+func (w *wrapper) ReportHistogram(name string, value float64, bounds []float64, tags []string) {
+	if hs, ok := w.next.(MetricsSinkHistograms); ok {
+		if name, tags, ok := w.transform(name, tags); ok {
+			hs.ReportHistogram(name, value, bounds, tags)
+		}
+	}
+}
+
+func (w *wrapper) ReportHistogramSampled(name string, value float64, rate float64, bounds []float64, tags []string) {
+	if name, tags, ok := w.transform(name, tags); ok {
+		if hss, ok := w.next.(MetricsSinkHistogramsSampled); ok {
+			hss.ReportHistogramSampled(name, value, rate, bounds, tags)
+		} else if hs, ok := w.next.(MetricsSinkHistograms); ok && ShouldSample(rate) {
+			hs.ReportHistogram(name, value, bounds, tags)
+		}
+	}
+}
+
+// Events aren't named like the metrics, so they're forwarded as they are
+func (w *wrapper) ReportEvent(event Event) {
+	if es, ok := w.next.(MetricsSinkEvents); ok {
+		es.ReportEvent(event)
+	}
+}
+
+func (w *wrapper) ReportServiceCheck(check ServiceCheck) {
+	if scs, ok := w.next.(MetricsSinkServiceChecks); ok {
+		if name, tags, ok := w.transform(check.Name, check.Tags); ok {
+			check.Name, check.Tags = name, tags
+			scs.ReportServiceCheck(check)
+		}
+	}
+}
+
+func (w *wrapper) reportCount(name string, value float64, tags []string) {
+	if ts, ok := w.next.(MetricsSinkTagged); ok && len(tags) > 0 {
+		ts.ReportCountTagged(name, value, tags)
+	} else {
+		w.next.ReportCount(name, value)
+	}
+}
+
+func (w *wrapper) reportDistribution(name string, value float64, tags []string) {
+	if ts, ok := w.next.(MetricsSinkTagged); ok && len(tags) > 0 {
+		ts.ReportDistributionTagged(name, value, tags)
+	} else {
+		w.next.ReportDistribution(name, value)
+	}
+}
@@ -0,0 +1,70 @@
+package simetrics
+
+import (
+	"time"
+)
+
+// Measures the total time of an operation and of each of its stages, reported all at once by `Stop()`.
+// It's not safe for concurrent use.
+type Timer struct {
+	m          *SiMetrics
+	name       string
+	start      time.Time
+	stage      string
+	stageStart time.Time
+	stages     map[string]time.Duration
+	stopped    bool
+}
+
+// Starts a timer for the operation `name`.
+// Example usage:
+// ```
+// t := metric.StartTimer("checkout")
+// t.Stage("db")
+// // query the database
+// t.Stage("render")
+// // render the response
+// t.Stop(err == nil)
+// ```
+// which reports the distributions `checkout`, `checkout.db` and `checkout.render`, plus a `checkout.success` count.
+func (m *SiMetrics) StartTimer(name string) *Timer {
+	return &Timer{m: m, name: name, start: time.Now(), stages: map[string]time.Duration{}}
+}
+
+// Ends the current stage, if any, and starts a new one. Stages with the same name are added up.
+func (t *Timer) Stage(name string) {
+	now := time.Now()
+	t.endStage(now)
+
+	t.stage = name
+	t.stageStart = now
+}
+
+// Stops the timer and reports the total and per-stage durations, in the `TimeUnit`, and either a `success` or a
+// `failure` count. Only the first call reports anything.
+func (t *Timer) Stop(success bool) {
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+
+	now := time.Now()
+	t.endStage(now)
+
+	t.m.Distribution(t.name, t.m.inTimeUnit(now.Sub(t.start)))
+	for stage, duration := range t.stages {
+		t.m.Distribution(t.name+"."+stage, t.m.inTimeUnit(duration))
+	}
+
+	if success {
+		t.m.Increment(t.name + ".success")
+	} else {
+		t.m.Increment(t.name + ".failure")
+	}
+}
+
+func (t *Timer) endStage(now time.Time) {
+	if t.stage != "" {
+		t.stages[t.stage] += now.Sub(t.stageStart)
+	}
+}
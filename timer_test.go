@@ -0,0 +1,66 @@
+package simetrics
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTimer(t *testing.T) {
+	Convey("A Timer", t, func() {
+		msMock := MetricsSinkMock{}
+		m, buildErr := NewBuilder(MetricsOptions{}, &msMock).Build()
+		So(buildErr, ShouldBeNil)
+
+		durations := map[string]float64{}
+		msMock.On("ReportDistribution", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			durations[args.String(0)] = args.Get(1).(float64)
+		})
+
+		Convey("should report the total and per-stage durations, and the outcome", func() {
+			msMock.OnReportCount("checkout.success", 1).Return().Once()
+
+			timer := m.StartTimer("checkout")
+			<-time.After(10 * time.Millisecond)
+			timer.Stage("db")
+			<-time.After(20 * time.Millisecond)
+			timer.Stage("render")
+			<-time.After(10 * time.Millisecond)
+			timer.Stop(true)
+
+			So(durations, ShouldHaveLength, 3)
+			// lower bounds only, since the sleeps may take longer on a loaded machine
+			So(durations["checkout"], ShouldBeGreaterThanOrEqualTo, 40)
+			So(durations["checkout.db"], ShouldBeGreaterThanOrEqualTo, 20)
+			So(durations["checkout.render"], ShouldBeGreaterThanOrEqualTo, 10)
+			So(durations["checkout"], ShouldBeGreaterThanOrEqualTo, durations["checkout.db"]+durations["checkout.render"])
+
+			Convey("only once", func() {
+				timer.Stop(false)
+				// The mock would cause an exception if there was an unexpected call
+			})
+		})
+
+		Convey("should report failures", func() {
+			msMock.OnReportCount("checkout.failure", 1).Return().Once()
+
+			m.StartTimer("checkout").Stop(false)
+			So(durations, ShouldContainKey, "checkout")
+		})
+
+		Convey("should report in the configured time unit", func() {
+			msMock.OnReportCount("checkout.success", 1).Return().Once()
+
+			m, buildErr := NewBuilder(MetricsOptions{TimeUnit: time.Microsecond}, &msMock).Build()
+			So(buildErr, ShouldBeNil)
+
+			timer := m.StartTimer("checkout")
+			<-time.After(10 * time.Millisecond)
+			timer.Stop(true)
+
+			So(durations["checkout"], ShouldBeGreaterThanOrEqualTo, 10000) // ~10 in milliseconds
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package cardinality
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	// Up to this many members are kept, giving an exact cardinality. After that it's estimated with a HyperLogLog.
+	ExactLimit = 10000
+
+	// The HyperLogLog uses 2^precision registers, for a standard error of 1.04/sqrt(2^precision) (~0.8%)
+	precision = 14
+	registers = 1 << precision
+)
+
+// Counts the distinct members added to it
+type Set struct {
+	members map[string]struct{}
+	hll     []uint8 // the HyperLogLog registers, only used after `ExactLimit` members
+}
+
+func New() *Set {
+	return &Set{members: map[string]struct{}{}}
+}
+
+func FromMember(member string) *Set {
+	s := New()
+	s.AddMember(member)
+	return s
+}
+
+func (s *Set) AddMember(member string) {
+	if s.hll != nil {
+		s.addHash(hash(member))
+		return
+	}
+
+	s.members[member] = struct{}{}
+	if len(s.members) > ExactLimit {
+		s.hll = make([]uint8, registers)
+		for m := range s.members {
+			s.addHash(hash(m))
+		}
+		s.members = nil
+	}
+}
+
+// Merges the members of another set into this one
+func (s *Set) Add(set *Set) {
+	if set.hll == nil {
+		for m := range set.members {
+			s.AddMember(m)
+		}
+		return
+	}
+
+	if s.hll == nil {
+		s.hll = make([]uint8, registers)
+		for m := range s.members {
+			s.addHash(hash(m))
+		}
+		s.members = nil
+	}
+	for i, rank := range set.hll {
+		if rank > s.hll[i] {
+			s.hll[i] = rank
+		}
+	}
+}
+
+// Returns whether the cardinality is an estimate rather than exact
+func (s *Set) IsEstimate() bool {
+	return s.hll != nil
+}
+
+// Returns the number of distinct members, exact up to `ExactLimit` and estimated after that
+func (s *Set) Cardinality() float64 {
+	if s.hll == nil {
+		return float64(len(s.members))
+	}
+
+	sum, zeros := 0.0, 0
+	for _, rank := range s.hll {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(registers)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 { // small range correction, with linear counting
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return estimate
+}
+
+func (s *Set) addHash(h uint64) {
+	index := h >> (64 - precision)
+	rank := uint8(bits.LeadingZeros64(h<<precision|1<<(precision-1)) + 1)
+	if rank > s.hll[index] {
+		s.hll[index] = rank
+	}
+}
+
+// FNV-1a followed by the murmur3 finalizer, as FNV alone doesn't spread similar strings well enough
+func hash(member string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,51 @@
+package cardinality
+
+import (
+	"strconv"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSet(t *testing.T) {
+	Convey("A Set", t, func() {
+		s := New()
+
+		Convey("should count the distinct members exactly up to the limit", func() {
+			for i := 0; i < ExactLimit; i++ {
+				s.AddMember("user-" + strconv.Itoa(i))
+				s.AddMember("user-" + strconv.Itoa(i))
+			}
+
+			So(s.IsEstimate(), ShouldBeFalse)
+			So(s.Cardinality(), ShouldEqual, ExactLimit)
+		})
+
+		Convey("should estimate the distinct members after the limit", func() {
+			for i := 0; i < 200000; i++ {
+				s.AddMember("user-" + strconv.Itoa(i%100000))
+			}
+
+			So(s.IsEstimate(), ShouldBeTrue)
+			So(s.Cardinality(), ShouldAlmostEqual, 100000, 3000)
+		})
+
+		Convey("should merge other sets", func() {
+			small, large := New(), New()
+			for i := 0; i < 100; i++ {
+				small.AddMember("small-" + strconv.Itoa(i))
+			}
+			for i := 0; i < 50000; i++ {
+				large.AddMember("large-" + strconv.Itoa(i))
+			}
+			s.AddMember("small-1")
+
+			s.Add(small)
+			So(s.Cardinality(), ShouldEqual, 100)
+
+			s.Add(large)
+			So(s.IsEstimate(), ShouldBeTrue)
+			So(s.Cardinality(), ShouldAlmostEqual, 50100, 1500)
+		})
+	})
+}
@@ -0,0 +1,156 @@
+package cardinality
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// The version of the binary and JSON encodings, increased on incompatible changes
+	EncodingVersion = 1
+
+	exactEncoding = 0
+	hllEncoding   = 1
+)
+
+var ErrInvalidEncoding = errors.New("invalid set encoding")
+
+// Encodes as the version byte followed by either:
+//   - 0, the number of members and each member prefixed by its length, as uvarints, while the set is exact
+//   - 1, the HyperLogLog precision and its registers, one byte each, once it's estimated
+func (s *Set) MarshalBinary() ([]byte, error) {
+	if s.hll != nil {
+		return append([]byte{EncodingVersion, hllEncoding, precision}, s.hll...), nil
+	}
+
+	b := []byte{EncodingVersion, exactEncoding}
+	b = binary.AppendUvarint(b, uint64(len(s.members)))
+	for _, member := range s.sortedMembers() {
+		b = binary.AppendUvarint(b, uint64(len(member)))
+		b = append(b, member...)
+	}
+	return b, nil
+}
+
+func (s *Set) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return ErrInvalidEncoding
+	}
+	if data[0] != EncodingVersion {
+		return fmt.Errorf("unsupported set encoding version %d", data[0])
+	}
+
+	if data[1] == hllEncoding {
+		if len(data) < 3 || data[2] != precision {
+			return ErrInvalidEncoding
+		}
+		return s.setRegisters(data[3:])
+	}
+	if data[1] != exactEncoding {
+		return ErrInvalidEncoding
+	}
+
+	data = data[2:]
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) { // every member takes at least one byte
+		return ErrInvalidEncoding
+	}
+	data = data[n:]
+
+	members := make([]string, count)
+	for i := range members {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return ErrInvalidEncoding
+		}
+		members[i] = string(data[n : n+int(length)])
+		data = data[n+int(length):]
+	}
+	if len(data) != 0 {
+		return ErrInvalidEncoding
+	}
+
+	return s.setMembers(members)
+}
+
+type setJSON struct {
+	Version   int      `json:"version"`
+	Members   [][]byte `json:"members,omitempty"` // base64 encoded, as they may not be valid UTF-8
+	Precision int      `json:"precision,omitempty"`
+	Registers []byte   `json:"registers,omitempty"` // base64 encoded
+}
+
+// Encodes the members while the set is exact, or the HyperLogLog registers once it's estimated
+func (s *Set) MarshalJSON() ([]byte, error) {
+	if s.hll != nil {
+		return json.Marshal(setJSON{Version: EncodingVersion, Precision: precision, Registers: s.hll})
+	}
+
+	sorted := s.sortedMembers()
+	members := make([][]byte, len(sorted))
+	for i, member := range sorted {
+		members[i] = []byte(member)
+	}
+	return json.Marshal(setJSON{Version: EncodingVersion, Members: members})
+}
+
+func (s *Set) UnmarshalJSON(data []byte) error {
+	var sj setJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	if sj.Version != EncodingVersion {
+		return fmt.Errorf("unsupported set encoding version %d", sj.Version)
+	}
+
+	if sj.Registers != nil {
+		if sj.Precision != precision {
+			return ErrInvalidEncoding
+		}
+		return s.setRegisters(sj.Registers)
+	}
+
+	members := make([]string, len(sj.Members))
+	for i, member := range sj.Members {
+		members[i] = string(member)
+	}
+	return s.setMembers(members)
+}
+
+func (s *Set) sortedMembers() []string {
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func (s *Set) setMembers(members []string) error {
+	if len(members) > ExactLimit {
+		return ErrInvalidEncoding
+	}
+
+	*s = Set{members: make(map[string]struct{}, len(members))}
+	for _, member := range members {
+		s.members[member] = struct{}{}
+	}
+	return nil
+}
+
+func (s *Set) setRegisters(data []byte) error {
+	if len(data) != registers {
+		return ErrInvalidEncoding
+	}
+	for _, rank := range data {
+		if rank > 64-precision+1 {
+			return ErrInvalidEncoding
+		}
+	}
+
+	*s = Set{hll: append([]uint8(nil), data...)}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cardinality
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func FuzzSetEncoding(f *testing.F) {
+	f.Add("", 0)
+	f.Add("a,b,c", 0)
+	f.Add("user", 20000)
+
+	f.Fuzz(func(t *testing.T, members string, generated int) {
+		s := New()
+		for _, member := range strings.Split(members, ",") {
+			s.AddMember(member)
+		}
+		for i := 0; i < generated%30000; i++ {
+			s.AddMember(strconv.Itoa(i))
+		}
+
+		b, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := New()
+		if err := decoded.UnmarshalBinary(b); err != nil || decoded.Cardinality() != s.Cardinality() {
+			t.Fatalf("binary round-trip gave %v instead of %v (%v)", decoded.Cardinality(), s.Cardinality(), err)
+		}
+
+		j, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded = New()
+		if err := json.Unmarshal(j, decoded); err != nil || decoded.Cardinality() != s.Cardinality() {
+			t.Fatalf("json round-trip gave %v instead of %v (%v)", decoded.Cardinality(), s.Cardinality(), err)
+		}
+
+		// merging the decoded set is the same as merging the original
+		merged, mergedDecoded := FromMember("other"), FromMember("other")
+		merged.Add(s)
+		mergedDecoded.Add(decoded)
+		if merged.Cardinality() != mergedDecoded.Cardinality() {
+			t.Fatalf("merge of decoded gave %v instead of %v", mergedDecoded.Cardinality(), merged.Cardinality())
+		}
+	})
+}
+
+func FuzzSetDecoding(f *testing.F) {
+	b, _ := FromMember("user-1").MarshalBinary()
+	f.Add(b)
+	f.Add([]byte{EncodingVersion, hllEncoding, precision})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := New()
+		if s.UnmarshalBinary(data) == nil {
+			s.AddMember("another")
+			_ = s.Cardinality()
+		}
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	Convey("A Set", t, func() {
+		Convey("should be encoded in JSON with its members while exact, base64 encoded", func() {
+			s := FromMember("b")
+			s.AddMember("a")
+
+			j, err := json.Marshal(s)
+			So(err, ShouldBeNil)
+			So(string(j), ShouldEqual, `{"version":1,"members":["YQ==","Yg=="]}`)
+
+			Convey("keeping the members that aren't valid UTF-8 apart", func() {
+				s := FromMember("\x9e")
+				s.AddMember("\xd1")
+
+				j, err := json.Marshal(s)
+				So(err, ShouldBeNil)
+				decoded := New()
+				So(json.Unmarshal(j, decoded), ShouldBeNil)
+				So(decoded.Cardinality(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("should reject other versions", func() {
+			b, _ := FromMember("a").MarshalBinary()
+			b[0] = EncodingVersion + 1
+			So(New().UnmarshalBinary(b), ShouldNotBeNil)
+			So(json.Unmarshal([]byte(`{"version":2,"members":["YQ=="]}`), New()), ShouldNotBeNil)
+		})
+	})
+}
@@ -6,41 +6,60 @@ import (
 )
 
 type Distribution struct {
-	N     float64
-	Min   float64
-	Max   float64
-	SumX  float64
-	SumX2 float64
+	N    float64
+	Min  float64
+	Max  float64
+	SumX float64
+	M2   float64 // the sum of the squared differences from the mean, kept with Welford's algorithm for stability
 }
 
 func FromValue(v float64) *Distribution {
-	return &Distribution{1, v, v, v, v * v}
+	return &Distribution{1, v, v, v, 0}
 }
 
 func (d *Distribution) AddEntry(v float64) {
-	d.Min = math.Min(d.Min, v)
-	d.Max = math.Max(d.Max, v)
-	d.SumX += v
-	d.SumX2 += v * v
-	d.N += 1
+	d.Add(&Distribution{1, v, v, v, 0})
 }
 
+// Adds a value that stands for `w` entries, e.g. a value sampled at 1% has a weight of 100
+func (d *Distribution) AddWeighted(v, w float64) {
+	d.Add(&Distribution{w, v, v, v * w, 0})
+}
+
+// Merges another distribution into this one, combining the M2 as in Chan et al. parallel algorithm
 func (d *Distribution) Add(dist *Distribution) {
+	if dist.N == 0 {
+		return
+	}
+	if d.N == 0 {
+		*d = *dist
+		return
+	}
+
+	delta := dist.Mean() - d.Mean()
+	n := d.N + dist.N
+
 	d.Min = math.Min(d.Min, dist.Min)
 	d.Max = math.Max(d.Max, dist.Max)
+	d.M2 += dist.M2 + delta*delta*d.N*dist.N/n
 	d.SumX += dist.SumX
-	d.SumX2 += dist.SumX2
-	d.N += dist.N
+	d.N = n
 }
 
 func (d *Distribution) Mean() float64 {
 	return d.SumX / float64(d.N)
 }
 
+// Returns the sum of the squared values, derived from the M2
+func (d *Distribution) SumX2() float64 {
+	if d.N == 0 {
+		return 0
+	}
+	return d.M2 + d.SumX*d.SumX/d.N
+}
+
 func (d *Distribution) Sd() float64 {
-	meanXSq := d.SumX2 / d.N
-	meanSq := math.Pow(d.Mean(), 2)
-	return math.Sqrt(math.Max(meanXSq-meanSq, 0))
+	return math.Sqrt(math.Max(d.M2/d.N, 0))
 }
 
 func (d *Distribution) String() string {
@@ -0,0 +1,115 @@
+package distribution
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// A straightforward two-pass computation, as the reference for the streaming one
+func referenceSd(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	sumSqDiff := 0.0
+	for _, v := range values {
+		sumSqDiff += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sumSqDiff / float64(len(values)))
+}
+
+func randomValues(r *rand.Rand, n int, offset, spread float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = offset + r.NormFloat64()*spread
+	}
+	return values
+}
+
+func TestDistribution(t *testing.T) {
+	Convey("A Distribution", t, func() {
+		r := rand.New(rand.NewSource(42))
+
+		Convey("should compute the basic stats", func() {
+			d := FromValue(2)
+			for _, v := range []float64{4, 4, 4, 5, 5, 7, 9} {
+				d.AddEntry(v)
+			}
+
+			So(d.N, ShouldEqual, 8)
+			So(d.Min, ShouldEqual, 2)
+			So(d.Max, ShouldEqual, 9)
+			So(d.Mean(), ShouldEqual, 5)
+			So(d.Sd(), ShouldEqual, 2)
+			So(d.SumX2(), ShouldAlmostEqual, 232)
+		})
+
+		Convey("should have a stable standard deviation, matching the reference implementation", func() {
+			for _, params := range []struct{ offset, spread float64 }{{0, 1}, {1e6, 1e-3}, {1e9, 1}, {-1e6, 10}, {1e12, 1e3}} {
+				for _, n := range []int{2, 10, 1000} {
+					values := randomValues(r, n, params.offset, params.spread)
+					d := FromValue(values[0])
+					for _, v := range values[1:] {
+						d.AddEntry(v)
+					}
+
+					So(d.Sd(), ShouldAlmostEqual, referenceSd(values), params.spread*1e-6)
+				}
+			}
+		})
+
+		Convey("should merge into the same result as adding every entry, regardless of the split", func() {
+			for i := 0; i < 100; i++ {
+				values := randomValues(r, 2+r.Intn(500), 1e6, 0.01)
+				split := 1 + r.Intn(len(values)-1)
+
+				all, left, right := &Distribution{}, &Distribution{}, &Distribution{}
+				for _, v := range values {
+					all.AddEntry(v)
+				}
+				for _, v := range values[:split] {
+					left.AddEntry(v)
+				}
+				for _, v := range values[split:] {
+					right.AddEntry(v)
+				}
+				left.Add(right)
+
+				So(left.N, ShouldEqual, all.N)
+				So(left.Min, ShouldEqual, all.Min)
+				So(left.Max, ShouldEqual, all.Max)
+				So(left.Sd(), ShouldAlmostEqual, referenceSd(values), 1e-8)
+				So(all.Sd(), ShouldAlmostEqual, referenceSd(values), 1e-8)
+			}
+		})
+
+		Convey("should support weighted entries", func() {
+			weighted, repeated := FromValue(1), FromValue(1)
+			weighted.AddWeighted(4, 3)
+			repeated.AddEntry(4)
+			repeated.AddEntry(4)
+			repeated.AddEntry(4)
+
+			So(weighted.N, ShouldEqual, 4)
+			So(weighted.SumX, ShouldEqual, 13)
+			So(weighted.Mean(), ShouldEqual, repeated.Mean())
+			So(weighted.Sd(), ShouldAlmostEqual, repeated.Sd())
+			So(weighted.SumX2(), ShouldAlmostEqual, 49)
+		})
+
+		Convey("should merge with empty distributions", func() {
+			d := FromValue(3)
+			d.Add(&Distribution{})
+			So(d, ShouldResemble, FromValue(3))
+
+			empty := &Distribution{}
+			empty.Add(FromValue(3))
+			So(empty, ShouldResemble, FromValue(3))
+		})
+	})
+}
@@ -0,0 +1,199 @@
+package distribution
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// The version of the binary and JSON encodings, increased on incompatible changes
+	EncodingVersion = 1
+
+	distributionBinarySize = 1 + 5*8
+)
+
+var ErrInvalidEncoding = errors.New("invalid distribution encoding")
+
+// Encodes as the version byte followed by N, Min, Max, SumX and M2 as little endian float64s
+func (d *Distribution) MarshalBinary() ([]byte, error) {
+	return d.appendBinary(make([]byte, 0, distributionBinarySize)), nil
+}
+
+func (d *Distribution) UnmarshalBinary(data []byte) error {
+	if len(data) != distributionBinarySize {
+		return ErrInvalidEncoding
+	}
+	if data[0] != EncodingVersion {
+		return fmt.Errorf("unsupported distribution encoding version %d", data[0])
+	}
+
+	return d.decodeBinary(data[1:])
+}
+
+func (d *Distribution) appendBinary(b []byte) []byte {
+	b = append(b, EncodingVersion)
+	for _, v := range []float64{d.N, d.Min, d.Max, d.SumX, d.M2} {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	return b
+}
+
+// Decodes the fields, without the version byte
+func (d *Distribution) decodeBinary(data []byte) error {
+	fields := make([]float64, 5)
+	for i := range fields {
+		fields[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+	*d = Distribution{fields[0], fields[1], fields[2], fields[3], fields[4]}
+
+	return d.validate()
+}
+
+func (d *Distribution) validate() error {
+	for _, v := range []float64{d.N, d.Min, d.Max, d.SumX, d.M2} {
+		if math.IsNaN(v) {
+			return ErrInvalidEncoding
+		}
+	}
+	if d.N < 0 || d.M2 < 0 {
+		return ErrInvalidEncoding
+	}
+	return nil
+}
+
+type distributionJSON struct {
+	Version int      `json:"version"`
+	N       float64  `json:"n"`
+	Min     *float64 `json:"min,omitempty"` // omitted on empty distributions, where it would be infinite
+	Max     *float64 `json:"max,omitempty"`
+	Sum     float64  `json:"sum"`
+	M2      float64  `json:"m2"`
+}
+
+func (d *Distribution) toJSON() distributionJSON {
+	dj := distributionJSON{Version: EncodingVersion, N: d.N, Sum: d.SumX, M2: d.M2}
+	if d.N > 0 {
+		dj.Min, dj.Max = &d.Min, &d.Max
+	}
+	return dj
+}
+
+func (dj distributionJSON) toDistribution() (Distribution, error) {
+	if dj.Version != EncodingVersion {
+		return Distribution{}, fmt.Errorf("unsupported distribution encoding version %d", dj.Version)
+	}
+
+	d := Distribution{N: dj.N, Min: math.Inf(1), Max: math.Inf(-1), SumX: dj.Sum, M2: dj.M2}
+	if dj.Min != nil && dj.Max != nil {
+		d.Min, d.Max = *dj.Min, *dj.Max
+	} else if d.N > 0 {
+		return Distribution{}, ErrInvalidEncoding
+	}
+
+	return d, d.validate()
+}
+
+func (d *Distribution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.toJSON())
+}
+
+func (d *Distribution) UnmarshalJSON(data []byte) error {
+	var dj distributionJSON
+	if err := json.Unmarshal(data, &dj); err != nil {
+		return err
+	}
+
+	decoded, err := dj.toDistribution()
+	if err != nil {
+		return err
+	}
+
+	*d = decoded
+	return nil
+}
+
+// Encodes as the version byte, the distribution fields, the number of bounds as an uvarint, the bounds and then the
+// counts, as little endian float64s
+func (h *Histogram) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, distributionBinarySize+binary.MaxVarintLen64+(2*len(h.Bounds)+1)*8)
+	b = h.Distribution.appendBinary(b)
+	b = binary.AppendUvarint(b, uint64(len(h.Bounds)))
+	for _, values := range [][]float64{h.Bounds, h.Counts} {
+		for _, v := range values {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		}
+	}
+	return b, nil
+}
+
+func (h *Histogram) UnmarshalBinary(data []byte) error {
+	if len(data) < distributionBinarySize {
+		return ErrInvalidEncoding
+	}
+
+	var dist Distribution
+	if err := dist.UnmarshalBinary(data[:distributionBinarySize]); err != nil {
+		return err
+	}
+
+	data = data[distributionBinarySize:]
+	numBounds, n := binary.Uvarint(data)
+	// checked before any arithmetic on numBounds, which could overflow
+	if n <= 0 || numBounds > uint64(len(data)-n)/16 || uint64(len(data)-n) != (2*numBounds+1)*8 {
+		return ErrInvalidEncoding
+	}
+
+	values := make([]float64, 2*numBounds+1)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[n+i*8:]))
+	}
+
+	return h.set(dist, values[:numBounds], values[numBounds:])
+}
+
+type histogramJSON struct {
+	distributionJSON
+	Bounds []float64 `json:"bounds"`
+	Counts []float64 `json:"counts"`
+}
+
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(histogramJSON{h.Distribution.toJSON(), h.Bounds, h.Counts})
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var hj histogramJSON
+	if err := json.Unmarshal(data, &hj); err != nil {
+		return err
+	}
+
+	dist, err := hj.distributionJSON.toDistribution()
+	if err != nil {
+		return err
+	}
+
+	return h.set(dist, hj.Bounds, hj.Counts)
+}
+
+// Validates and sets the decoded fields
+func (h *Histogram) set(dist Distribution, bounds, counts []float64) error {
+	if len(counts) != len(bounds)+1 {
+		return ErrInvalidEncoding
+	}
+	for i := range bounds {
+		if math.IsNaN(bounds[i]) || (i > 0 && bounds[i] < bounds[i-1]) {
+			return ErrInvalidEncoding
+		}
+	}
+	for _, count := range counts {
+		if math.IsNaN(count) || count < 0 {
+			return ErrInvalidEncoding
+		}
+	}
+
+	*h = Histogram{Bounds: bounds, Counts: counts, Distribution: dist}
+	return nil
+}
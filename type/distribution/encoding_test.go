@@ -0,0 +1,167 @@
+package distribution
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Decodes the fuzzer bytes as a list of finite float64s
+func fuzzValues(data []byte) []float64 {
+	values := []float64{}
+	for ; len(data) >= 8; data = data[8:] {
+		v := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		if !math.IsNaN(v) && !math.IsInf(v, 0) && math.Abs(v) < 1e100 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func FuzzDistributionEncoding(f *testing.F) {
+	f.Add([]byte{}, 0)
+	f.Add(binary.LittleEndian.AppendUint64(nil, math.Float64bits(42)), 0)
+	f.Add(binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, math.Float64bits(-1.5)), math.Float64bits(1e6)), 1)
+
+	f.Fuzz(func(t *testing.T, data []byte, split int) {
+		values := fuzzValues(data)
+		if split < 0 || split > len(values) {
+			split = len(values) / 2
+		}
+
+		left, right, all := &Distribution{}, &Distribution{}, &Distribution{}
+		for i, v := range values {
+			if i < split {
+				left.AddEntry(v)
+			} else {
+				right.AddEntry(v)
+			}
+			all.AddEntry(v)
+		}
+
+		for _, d := range []*Distribution{left, right, all} {
+			b, err := d.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded Distribution
+			if err := decoded.UnmarshalBinary(b); err != nil || decoded != *d {
+				t.Fatalf("binary round-trip of %+v gave %+v (%v)", *d, decoded, err)
+			}
+
+			j, err := json.Marshal(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded = Distribution{}
+			if err := json.Unmarshal(j, &decoded); err != nil || (decoded != *d && d.N > 0) {
+				t.Fatalf("json round-trip of %+v gave %+v (%v)", *d, decoded, err)
+			}
+		}
+
+		// merging the decoded halves is the same as merging the originals
+		var decodedLeft, decodedRight Distribution
+		b, _ := left.MarshalBinary()
+		_ = decodedLeft.UnmarshalBinary(b)
+		b, _ = right.MarshalBinary()
+		_ = decodedRight.UnmarshalBinary(b)
+
+		decodedLeft.Add(&decodedRight)
+		left.Add(right)
+		if decodedLeft != *left {
+			t.Fatalf("merge of decoded %+v differs from %+v", decodedLeft, *left)
+		}
+		if left.N != all.N || left.SumX2() < 0 {
+			t.Fatalf("merge %+v differs from %+v", *left, *all)
+		}
+	})
+}
+
+func FuzzHistogramDecoding(f *testing.F) {
+	h := NewHistogram([]float64{1, 10, 100})
+	h.AddEntry(5)
+	b, _ := h.MarshalBinary()
+	f.Add(b)
+	f.Add([]byte{EncodingVersion})
+	// a bounds count overflowing the length check
+	overflow := binary.AppendUvarint(append([]byte{}, b[:distributionBinarySize]...), 1<<62)
+	f.Add(append(overflow, make([]byte, 8)...))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var h Histogram
+		if h.UnmarshalBinary(data) != nil {
+			return
+		}
+
+		b, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Histogram
+		if err := decoded.UnmarshalBinary(b); err != nil || !reflect.DeepEqual(decoded, h) {
+			t.Fatalf("re-encoding %+v gave %+v (%v)", h, decoded, err)
+		}
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	Convey("A Histogram", t, func() {
+		h := NewHistogram(ExponentialBounds(1, 10, 3))
+		for _, v := range []float64{0.5, 5, 50, 500} {
+			h.AddEntry(v)
+		}
+
+		Convey("should round-trip through its binary encoding", func() {
+			b, err := h.MarshalBinary()
+			So(err, ShouldBeNil)
+
+			decoded := &Histogram{}
+			So(decoded.UnmarshalBinary(b), ShouldBeNil)
+			So(decoded, ShouldResemble, h)
+		})
+
+		Convey("should round-trip through its JSON encoding", func() {
+			j, err := json.Marshal(h)
+			So(err, ShouldBeNil)
+			So(string(j), ShouldEqual, `{"version":1,"n":4,"min":0.5,"max":500,"sum":555.5,"m2":`+
+				mustJSON(h.M2)+`,"bounds":[1,10,100],"counts":[1,1,1,1]}`)
+
+			decoded := &Histogram{}
+			So(json.Unmarshal(j, decoded), ShouldBeNil)
+			So(decoded, ShouldResemble, h)
+		})
+
+		Convey("should merge after being decoded", func() {
+			b, _ := h.MarshalBinary()
+			decoded := &Histogram{}
+			So(decoded.UnmarshalBinary(b), ShouldBeNil)
+
+			So(decoded.Add(h), ShouldBeNil)
+			So(decoded.CumulativeCounts(), ShouldResemble, []float64{2, 4, 6, 8})
+		})
+	})
+
+	Convey("The decoding", t, func() {
+		Convey("should reject other versions", func() {
+			b, _ := FromValue(1).MarshalBinary()
+			b[0] = EncodingVersion + 1
+			So(new(Distribution).UnmarshalBinary(b), ShouldNotBeNil)
+			So(json.Unmarshal([]byte(`{"version":2,"n":1,"min":1,"max":1,"sum":1,"m2":0}`), new(Distribution)), ShouldNotBeNil)
+		})
+
+		Convey("should reject invalid data", func() {
+			So(new(Distribution).UnmarshalBinary([]byte{EncodingVersion, 0}), ShouldNotBeNil)
+			So(json.Unmarshal([]byte(`{"version":1,"n":1,"sum":1,"m2":0}`), new(Distribution)), ShouldNotBeNil)
+			So(json.Unmarshal([]byte(`{"version":1,"n":1,"min":1,"max":1,"sum":1,"m2":0,"bounds":[1],"counts":[1]}`), new(Histogram)), ShouldNotBeNil)
+		})
+	})
+}
+
+func mustJSON(v interface{}) string {
+	j, _ := json.Marshal(v)
+	return string(j)
+}
@@ -0,0 +1,100 @@
+package distribution
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A histogram with fixed buckets. Each bucket counts the values lower or equal to its bound (and greater than the
+// bound of the previous one), with an extra last bucket for the values greater than all the bounds.
+type Histogram struct {
+	Bounds []float64 // the upper bounds of the buckets, sorted
+	Counts []float64 // the count of each bucket, not cumulative. It has one more element than `Bounds`.
+	Distribution
+}
+
+// Returns `count` bounds, starting at `start` with `width` between each one, e.g. 100, 200, 300...
+func LinearBounds(start, width float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start + float64(i)*width
+	}
+	return bounds
+}
+
+// Returns `count` bounds, starting at `start` and multiplied by `factor` each time, e.g. 1, 2, 4, 8...
+func ExponentialBounds(start, factor float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start * math.Pow(factor, float64(i))
+	}
+	return bounds
+}
+
+// Returns an empty histogram with the given bucket bounds, which don't need to be sorted
+func NewHistogram(bounds []float64) *Histogram {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+
+	return &Histogram{
+		Bounds:       sorted,
+		Counts:       make([]float64, len(sorted)+1),
+		Distribution: Distribution{Min: math.Inf(1), Max: math.Inf(-1)},
+	}
+}
+
+func (h *Histogram) AddEntry(v float64) {
+	h.Counts[sort.SearchFloat64s(h.Bounds, v)]++
+	h.Distribution.AddEntry(v)
+}
+
+// Adds a value that stands for `w` entries, counting it `w` times in its bucket. See `Distribution.AddWeighted`.
+func (h *Histogram) AddWeighted(v, w float64) {
+	h.Counts[sort.SearchFloat64s(h.Bounds, v)] += w
+	h.Distribution.AddWeighted(v, w)
+}
+
+// Merges another histogram into this one, they must have the same bounds
+func (h *Histogram) Add(hist *Histogram) error {
+	if len(h.Bounds) != len(hist.Bounds) {
+		return errors.New("can't merge histograms with different bounds")
+	}
+	for i := range h.Bounds {
+		if h.Bounds[i] != hist.Bounds[i] {
+			return errors.New("can't merge histograms with different bounds")
+		}
+	}
+
+	for i := range h.Counts {
+		h.Counts[i] += hist.Counts[i]
+	}
+	h.Distribution.Add(&hist.Distribution)
+
+	return nil
+}
+
+// Returns the count of values lower or equal to each bound, with the total count as the last element
+func (h *Histogram) CumulativeCounts() []float64 {
+	cumulative := make([]float64, len(h.Counts))
+	sum := 0.0
+	for i, count := range h.Counts {
+		sum += count
+		cumulative[i] = sum
+	}
+	return cumulative
+}
+
+func (h *Histogram) String() string {
+	buckets := make([]string, len(h.Counts))
+	for i, count := range h.CumulativeCounts() {
+		bound := math.Inf(1)
+		if i < len(h.Bounds) {
+			bound = h.Bounds[i]
+		}
+		buckets[i] = fmt.Sprintf("le %.4g: %.4g", bound, count)
+	}
+	return fmt.Sprintf("Histogram: %s, %s", strings.Join(buckets, ", "), h.Distribution.String())
+}
@@ -0,0 +1,60 @@
+package distribution
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistogram(t *testing.T) {
+	Convey("The bounds helpers", t, func() {
+		So(LinearBounds(100, 50, 4), ShouldResemble, []float64{100, 150, 200, 250})
+		So(ExponentialBounds(1, 2, 5), ShouldResemble, []float64{1, 2, 4, 8, 16})
+	})
+
+	Convey("A Histogram", t, func() {
+		h := NewHistogram([]float64{300, 100, 200})
+
+		Convey("should sort its bounds", func() {
+			So(h.Bounds, ShouldResemble, []float64{100, 200, 300})
+		})
+
+		Convey("should count the values lower or equal to each bound", func() {
+			for _, v := range []float64{50, 100, 101, 250, 300, 1000} {
+				h.AddEntry(v)
+			}
+
+			So(h.Counts, ShouldResemble, []float64{2, 1, 2, 1})
+			So(h.CumulativeCounts(), ShouldResemble, []float64{2, 3, 5, 6})
+			So(h.N, ShouldEqual, 6)
+			So(h.Min, ShouldEqual, 50)
+			So(h.Max, ShouldEqual, 1000)
+		})
+
+		Convey("should count the weighted values in their bucket", func() {
+			h.AddWeighted(150, 100)
+			h.AddEntry(50)
+
+			So(h.Counts, ShouldResemble, []float64{1, 100, 0, 0})
+			So(h.N, ShouldEqual, 101)
+		})
+
+		Convey("should merge histograms with the same bounds", func() {
+			other := NewHistogram([]float64{100, 200, 300})
+			h.AddEntry(10)
+			other.AddEntry(150)
+			other.AddEntry(5000)
+
+			So(h.Add(other), ShouldBeNil)
+			So(h.Counts, ShouldResemble, []float64{1, 1, 0, 1})
+			So(h.N, ShouldEqual, 3)
+			So(h.Min, ShouldEqual, 10)
+			So(h.Max, ShouldEqual, 5000)
+		})
+
+		Convey("should not merge histograms with different bounds", func() {
+			So(h.Add(NewHistogram([]float64{100, 200})), ShouldNotBeNil)
+			So(h.Add(NewHistogram([]float64{100, 200, 400})), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package distribution
+
+import (
+	"sync"
+	"time"
+)
+
+// A distribution of the values added over the last `window` duration. The window is split in slots, which are
+// discarded as a whole once they're older than the window. It's safe for concurrent use.
+type Window struct {
+	mutex        sync.Mutex
+	slotDuration time.Duration
+	slots        []Distribution
+	slotIndexes  []int64 // the index since the epoch of the period of each slot, to know when it's stale
+	now          func() time.Time
+}
+
+// Returns a distribution over the last `window`, with the given number of slots
+// (e.g. a 1 minute window with 6 slots covers from the last 50s to the last 60s)
+func NewWindow(window time.Duration, slots int) *Window {
+	return newWindowWithClock(window, slots, time.Now)
+}
+
+func newWindowWithClock(window time.Duration, slots int, now func() time.Time) *Window {
+	if slots < 1 {
+		slots = 1
+	}
+	slotDuration := window / time.Duration(slots)
+	if slotDuration < 1 { // e.g. for an empty window, which then only keeps the values of the current nanosecond
+		slotDuration = 1
+	}
+
+	return &Window{
+		slotDuration: slotDuration,
+		slots:        make([]Distribution, slots),
+		slotIndexes:  make([]int64, slots),
+		now:          now,
+	}
+}
+
+func (w *Window) AddEntry(v float64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	index := w.now().UnixNano() / int64(w.slotDuration)
+	slot := index % int64(len(w.slots))
+	if w.slotIndexes[slot] != index {
+		w.slots[slot] = Distribution{}
+		w.slotIndexes[slot] = index
+	}
+	w.slots[slot].AddEntry(v)
+}
+
+// Returns the distribution of the values added within the window
+func (w *Window) Snapshot() *Distribution {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	index := w.now().UnixNano() / int64(w.slotDuration)
+	snapshot := &Distribution{}
+	for slot := range w.slots {
+		if index-w.slotIndexes[slot] < int64(len(w.slots)) {
+			snapshot.Add(&w.slots[slot])
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,54 @@
+package distribution
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWindow(t *testing.T) {
+	Convey("A Window", t, func() {
+		now := time.Unix(1000, 0)
+		w := newWindowWithClock(time.Minute, 6, func() time.Time { return now })
+
+		Convey("should only keep the values within the window", func() {
+			w.AddEntry(100)
+			now = now.Add(30 * time.Second)
+			w.AddEntry(1)
+			w.AddEntry(3)
+
+			snapshot := w.Snapshot()
+			So(snapshot.N, ShouldEqual, 3)
+			So(snapshot.Max, ShouldEqual, 100)
+
+			now = now.Add(40 * time.Second)
+			snapshot = w.Snapshot()
+			So(snapshot.N, ShouldEqual, 2)
+			So(snapshot.Mean(), ShouldEqual, 2)
+
+			now = now.Add(time.Hour)
+			So(w.Snapshot().N, ShouldEqual, 0)
+		})
+
+		Convey("should reuse the slots as time goes by", func() {
+			for i := 0; i < 120; i++ {
+				w.AddEntry(float64(i))
+				now = now.Add(time.Second)
+			}
+
+			snapshot := w.Snapshot()
+			So(snapshot.N, ShouldEqual, 50) // the current slot is empty
+			So(snapshot.Min, ShouldEqual, 70)
+			So(snapshot.Max, ShouldEqual, 119)
+		})
+
+		Convey("should not fail with a window shorter than its slots", func() {
+			for _, window := range []time.Duration{0, 5, -time.Second} {
+				w := newWindowWithClock(window, 6, func() time.Time { return now })
+				w.AddEntry(1)
+				So(w.Snapshot().N, ShouldEqual, 1)
+			}
+		})
+	})
+}
@@ -0,0 +1,120 @@
+package meter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// The rates are updated every 5s, like the unix load averages
+	TickInterval = 5 * time.Second
+)
+
+var (
+	// The smoothing factors of the 1, 5 and 15 minutes exponentially weighted moving averages
+	alphas = [3]float64{
+		1 - math.Exp(-TickInterval.Minutes()/1),
+		1 - math.Exp(-TickInterval.Minutes()/5),
+		1 - math.Exp(-TickInterval.Minutes()/15),
+	}
+)
+
+// Measures the rate of events per second: the mean rate and exponentially weighted moving averages over the last
+// 1, 5 and 15 minutes, like the go-metrics Meter. The rates are updated lazily, without any background goroutine.
+// It's safe for concurrent use.
+type Meter struct {
+	mutex       sync.Mutex
+	count       float64
+	uncounted   float64 // events since the last tick
+	rates       [3]float64
+	initialized bool
+	start       time.Time
+	lastTick    time.Time
+	now         func() time.Time
+}
+
+func New() *Meter {
+	return newWithClock(time.Now)
+}
+
+func newWithClock(now func() time.Time) *Meter {
+	start := now()
+	return &Meter{start: start, lastTick: start, now: now}
+}
+
+// Records `n` events
+func (mt *Meter) Mark(n float64) {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+
+	mt.tickIfNecessary()
+	mt.count += n
+	mt.uncounted += n
+}
+
+// The total number of events
+func (mt *Meter) Count() float64 {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+
+	return mt.count
+}
+
+// The events per second over the last minute
+func (mt *Meter) Rate1() float64 {
+	return mt.rate(0)
+}
+
+// The events per second over the last 5 minutes
+func (mt *Meter) Rate5() float64 {
+	return mt.rate(1)
+}
+
+// The events per second over the last 15 minutes
+func (mt *Meter) Rate15() float64 {
+	return mt.rate(2)
+}
+
+// The events per second since the meter was created
+func (mt *Meter) RateMean() float64 {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+
+	elapsed := mt.now().Sub(mt.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return mt.count / elapsed
+}
+
+func (mt *Meter) rate(i int) float64 {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+
+	mt.tickIfNecessary()
+	return mt.rates[i]
+}
+
+// Updates the moving averages for every `TickInterval` elapsed since the last update
+func (mt *Meter) tickIfNecessary() {
+	ticks := int64(mt.now().Sub(mt.lastTick) / TickInterval)
+	if ticks <= 0 {
+		return
+	}
+	mt.lastTick = mt.lastTick.Add(time.Duration(ticks) * TickInterval)
+
+	instantRate := mt.uncounted / TickInterval.Seconds()
+	mt.uncounted = 0
+
+	for i, alpha := range alphas {
+		if mt.initialized {
+			mt.rates[i] += alpha * (instantRate - mt.rates[i])
+		} else {
+			mt.rates[i] = instantRate
+		}
+		// no events happened on the remaining ticks, so the rate just decays
+		mt.rates[i] *= math.Pow(1-alpha, float64(ticks-1))
+	}
+	mt.initialized = true
+}
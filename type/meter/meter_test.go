@@ -0,0 +1,54 @@
+package meter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMeter(t *testing.T) {
+	Convey("A Meter", t, func() {
+		now := time.Unix(1000, 0)
+		mt := newWithClock(func() time.Time { return now })
+
+		Convey("should count the events", func() {
+			mt.Mark(3)
+			mt.Mark(2)
+			So(mt.Count(), ShouldEqual, 5)
+			So(mt.Rate1(), ShouldEqual, 0) // no tick yet
+
+			now = now.Add(10 * time.Second)
+			So(mt.RateMean(), ShouldEqual, 0.5)
+		})
+
+		Convey("should start the moving averages at the first rate", func() {
+			mt.Mark(60)
+			now = now.Add(TickInterval)
+
+			So(mt.Rate1(), ShouldEqual, 12)
+			So(mt.Rate5(), ShouldEqual, 12)
+			So(mt.Rate15(), ShouldEqual, 12)
+
+			Convey("and decay them when idle", func() {
+				now = now.Add(time.Minute)
+
+				So(mt.Rate1(), ShouldAlmostEqual, 12*math.Exp(-1))
+				So(mt.Rate5(), ShouldAlmostEqual, 12*math.Exp(-1.0/5))
+				So(mt.Rate15(), ShouldAlmostEqual, 12*math.Exp(-1.0/15))
+			})
+		})
+
+		Convey("should converge to a steady rate", func() {
+			for i := 0; i < 15*60; i++ {
+				mt.Mark(2)
+				now = now.Add(time.Second)
+			}
+
+			So(mt.Rate1(), ShouldAlmostEqual, 2, 0.01)
+			So(mt.Rate5(), ShouldAlmostEqual, 2, 0.01)
+			So(mt.Rate15(), ShouldAlmostEqual, 2, 0.01)
+		})
+	})
+}